@@ -0,0 +1,196 @@
+// Package githubapp authenticates as an installation of a GitHub App.
+// https://developer.github.com/apps/building-github-apps/authenticating-with-github-apps/
+package githubapp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// DefaultBaseURL is the API url used when one is not specified.
+const DefaultBaseURL = "https://api.github.com/"
+
+const (
+	// jwtLifetime is how long a JWT is valid. GitHub allows at most 10 minutes.
+	jwtLifetime = 9 * time.Minute
+	// jwtClockSkew is how far a JWT is backdated to allow for clock drift.
+	jwtClockSkew = time.Minute
+	// refreshMargin is how long before its expiry an installation token is refreshed.
+	refreshMargin = 5 * time.Minute
+)
+
+// FromConfig returns a token source for the installation specified by the
+// "app_id", "installation_id" and "private_key" (PEM) or "private_key_file"
+// config values. baseURL is the API url, defaulting to DefaultBaseURL.
+func FromConfig(conf map[string]string, baseURL string, c *http.Client) (oauth2.TokenSource, error) {
+	appID := conf["app_id"]
+	if len(appID) == 0 {
+		return nil, fmt.Errorf("app_id required for github_app auth")
+	}
+
+	installationID := conf["installation_id"]
+	if len(installationID) == 0 {
+		return nil, fmt.Errorf("installation_id required for github_app auth")
+	}
+
+	key := []byte(conf["private_key"])
+	if fp := conf["private_key_file"]; len(key) == 0 && len(fp) != 0 {
+		var err error
+		if key, err = ioutil.ReadFile(fp); err != nil {
+			return nil, fmt.Errorf("failed to read private key: %+v", err)
+		}
+	}
+
+	if len(key) == 0 {
+		return nil, fmt.Errorf("private_key or private_key_file required for github_app auth")
+	}
+
+	return NewTokenSource(appID, installationID, key, baseURL, c)
+}
+
+// NewTokenSource is a constructor that returns a token source for the
+// installation of an app, authenticated with the PEM encoded private key of the
+// app. Installation tokens are cached and refreshed before they expire. If the
+// http client is nil, http.DefaultClient is used.
+func NewTokenSource(appID string, installationID string, pemKey []byte, baseURL string, c *http.Client) (oauth2.TokenSource, error) {
+	key, err := parsePrivateKey(pemKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(baseURL) == 0 {
+		baseURL = DefaultBaseURL
+	}
+
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return oauth2.ReuseTokenSource(nil, &tokenSource{
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		httpClient:     c,
+		now:            time.Now,
+	}), nil
+}
+
+func parsePrivateKey(b []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode private key: no PEM data")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %+v", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key must be an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// tokenSource exchanges JWTs for installation tokens.
+type tokenSource struct {
+	appID          string
+	installationID string
+	key            *rsa.PrivateKey
+	baseURL        string
+	httpClient     *http.Client
+	now            func() time.Time
+}
+
+// Token returns a new installation token. Its expiry is moved forward by
+// refreshMargin so that it is refreshed before GitHub rejects it.
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.jwt()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWT: %+v", err)
+	}
+
+	u := fmt.Sprintf("%s/app/installations/%s/access_tokens", s.baseURL, s.installationID)
+	req, err := http.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github.machine-man-preview+json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request installation token: %+v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to request installation token: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var res struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("failed to decode installation token: %+v", err)
+	}
+
+	return &oauth2.Token{
+		AccessToken: res.Token,
+		TokenType:   "token",
+		Expiry:      res.ExpiresAt.Add(-refreshMargin),
+	}, nil
+}
+
+// jwt returns a JWT signed with RS256 that authenticates as the app.
+func (s *tokenSource) jwt() (string, error) {
+	now := s.now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-jwtClockSkew).Unix(),
+		"exp": now.Add(jwtLifetime).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	h := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, h[:])
+	if err != nil {
+		return "", err
+	}
+
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
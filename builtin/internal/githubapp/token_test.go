@@ -0,0 +1,172 @@
+package githubapp
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTokenServer returns a token endpoint that verifies the JWT of every request
+// and issues tokens that expire after lifetime.
+func newTokenServer(t *testing.T, key *rsa.PrivateKey, lifetime time.Duration, requests *int) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			http.NotFound(w, r)
+			return
+		}
+
+		parts := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		if len(parts) != 3 {
+			http.Error(w, "malformed JWT", http.StatusUnauthorized)
+			return
+		}
+
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			http.Error(w, "malformed signature", http.StatusUnauthorized)
+			return
+		}
+
+		h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, h[:], sig); err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		b, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var claims struct {
+			Iss string `json:"iss"`
+		}
+		if err := json.Unmarshal(b, &claims); err != nil || claims.Iss != "7" {
+			http.Error(w, "invalid issuer", http.StatusUnauthorized)
+			return
+		}
+
+		*requests++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "token%d", "expires_at": "%s"}`, *requests, time.Now().Add(lifetime).UTC().Format(time.RFC3339))
+	}))
+}
+
+func Test_NewTokenSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %+v", err)
+	}
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	type input struct {
+		lifetime time.Duration
+		key      []byte
+	}
+
+	type want struct {
+		tokens []string
+		err    error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"cached": {
+			input: input{lifetime: time.Hour, key: pemKey},
+			want:  want{tokens: []string{"token1", "token1"}},
+		},
+
+		"refreshed_before_expiry": {
+			input: input{lifetime: refreshMargin - time.Minute, key: pemKey},
+			want:  want{tokens: []string{"token1", "token2"}},
+		},
+
+		"invalid_key": {
+			input: input{lifetime: time.Hour, key: []byte("not a key")},
+			want:  want{err: fmt.Errorf("failed to decode private key: no PEM data")},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var requests int
+			srv := newTokenServer(t, key, tt.input.lifetime, &requests)
+			defer srv.Close()
+
+			ts, gotErr := NewTokenSource("7", "42", tt.input.key, srv.URL+"/", nil)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("NewTokenSource() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			for i, want := range tt.want.tokens {
+				got, err := ts.Token()
+				if err != nil {
+					t.Fatalf("Token() unexpected error: %+v", err)
+				}
+
+				if got.AccessToken != want {
+					t.Errorf("Token() call %d: \n\tgot: '%+v'\n\twant: '%+v'", i, got.AccessToken, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_FromConfig(t *testing.T) {
+	var tests = map[string]struct {
+		input map[string]string
+		want  error
+	}{
+		"missing_app_id": {
+			input: map[string]string{"installation_id": "42", "private_key": "key"},
+			want:  fmt.Errorf("app_id required for github_app auth"),
+		},
+
+		"missing_installation_id": {
+			input: map[string]string{"app_id": "7", "private_key": "key"},
+			want:  fmt.Errorf("installation_id required for github_app auth"),
+		},
+
+		"missing_private_key": {
+			input: map[string]string{"app_id": "7", "installation_id": "42"},
+			want:  fmt.Errorf("private_key or private_key_file required for github_app auth"),
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, gotErr := FromConfig(tt.input, "", nil)
+
+			if gotErr == nil || gotErr.Error() != tt.want.Error() {
+				t.Errorf("FromConfig() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want)
+			}
+		})
+	}
+}
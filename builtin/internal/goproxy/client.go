@@ -0,0 +1,142 @@
+// Package goproxy is a minimal client for the Go module proxy protocol.
+// https://golang.org/cmd/go/#hdr-Module_proxy_protocol
+package goproxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"golang.org/x/mod/module"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// DefaultProxy is the proxy used when one is not specified.
+const DefaultProxy = "https://proxy.golang.org"
+
+// Client is a client for a single Go module proxy. Both remote (i.e., http and
+// https) and local (i.e., file://) proxies are supported.
+type Client struct {
+	proxy      string
+	httpClient *http.Client
+}
+
+// NewClient is a constructor that returns a pointer to a Client for the proxy
+// at the specified URL. If the http client is nil, http.DefaultClient is used.
+func NewClient(proxy string, c *http.Client) (*Client, error) {
+	if len(proxy) == 0 {
+		proxy = DefaultProxy
+	}
+
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proxy url: %+v", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "file":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s'", u.Scheme)
+	}
+
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return &Client{
+		proxy:      strings.TrimSuffix(proxy, "/"),
+		httpClient: c,
+	}, nil
+}
+
+// List returns the known versions of the module at path.
+func (c *Client) List(ctx context.Context, path string) ([]string, error) {
+	escaped, err := module.EscapePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := c.open(ctx, fmt.Sprintf("%s/@v/list", escaped))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var versions []string
+	s := bufio.NewScanner(rc)
+	for s.Scan() {
+		if v := strings.TrimSpace(s.Text()); v != "" {
+			versions = append(versions, v)
+		}
+	}
+
+	return versions, s.Err()
+}
+
+// Zip writes the module zip of path at version to w.
+func (c *Client) Zip(ctx context.Context, path string, version string, w io.Writer) error {
+	escapedPath, err := module.EscapePath(path)
+	if err != nil {
+		return err
+	}
+
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return err
+	}
+
+	rc, err := c.open(ctx, fmt.Sprintf("%s/@v/%s.zip", escapedPath, escapedVersion))
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	_, err = io.Copy(w, rc)
+	return err
+}
+
+func (c *Client) open(ctx context.Context, p string) (io.ReadCloser, error) {
+	if strings.HasPrefix(c.proxy, "file://") {
+		return os.Open(strings.TrimPrefix(c.proxy, "file://") + "/" + p)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.proxy+"/"+p, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		err := fmt.Errorf("unexpected response from proxy for '%s': %s", p, resp.Status)
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, &retrieval.TransientError{Err: err}
+		}
+
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// SplitModuleVersion splits a `module@version` string into its module path and
+// version.
+func SplitModuleVersion(s string) (string, string, error) {
+	i := strings.LastIndex(s, "@")
+	if i <= 0 || i == len(s)-1 {
+		return "", "", fmt.Errorf("'%s' must be of the form module@version", s)
+	}
+
+	return s[:i], s[i+1:], nil
+}
@@ -0,0 +1,107 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/src-d/go-billy.v4"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// Factory is a function for creating generic projects, i.e., projects that are
+// not tied to a specific hosting service (e.g., Go modules, local directories, etc.).
+func Factory(ctx context.Context, conf *project.BackendConfig) (project.Backend, error) {
+	if len(conf.Name) == 0 {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+
+	if len(conf.SourceLocation) == 0 {
+		return nil, fmt.Errorf("source location cannot be empty")
+	}
+
+	return &Backend{
+		name:           conf.Name,
+		version:        conf.Version,
+		sourceLocation: conf.SourceLocation,
+		retrievalFunc:  conf.RetrievalFunc,
+		metadata:       conf.Metadata.Clone(),
+	}, nil
+}
+
+// Backend is a generic project backend.
+type Backend struct {
+	name           string
+	retrievalFunc  retrieval.Backend
+	version        string
+	sourceLocation string
+	localLocation  string
+	filesystem     billy.Filesystem
+	metadata       *project.Metadata
+}
+
+// Name returns the name associated with a project.
+func (b *Backend) Name() string {
+	return b.name
+}
+
+// Version returns the version of the observed project (e.g., commit hash, semantic version, etc.).
+func (b *Backend) Version() string {
+	return b.version
+}
+
+// RetrievalFunc is the retrieval function that should be used to retrieve the project.
+// An example retrieval function could be a Go module proxy.
+func (b *Backend) RetrievalFunc() retrieval.Backend {
+	return b.retrievalFunc
+}
+
+// SourceLocation is the source location, i.e., where the project was discovered (e.g., a Go module proxy).
+func (b *Backend) SourceLocation() string {
+	return b.sourceLocation
+}
+
+// LocalLocation is the location on disk or where the project can be found in order
+// to perform and evaluation or analysis of the project.
+func (b *Backend) LocalLocation() string {
+	return b.localLocation
+}
+
+// SetLocalLocation sets the local or on-disk location.
+func (b *Backend) SetLocalLocation(l string) project.Backend {
+	return &Backend{
+		name:           b.Name(),
+		retrievalFunc:  b.RetrievalFunc(),
+		version:        b.Version(),
+		sourceLocation: b.SourceLocation(),
+		localLocation:  l,
+		filesystem:     b.Filesystem(),
+		metadata:       b.metadata,
+	}
+}
+
+// Filesystem returns the in-memory filesystem of the project, if it was retrieved
+// into memory.
+func (b *Backend) Filesystem() billy.Filesystem {
+	return b.filesystem
+}
+
+// SetFilesystem sets the in-memory filesystem.
+func (b *Backend) SetFilesystem(fs billy.Filesystem) project.Backend {
+	return &Backend{
+		name:           b.Name(),
+		retrievalFunc:  b.RetrievalFunc(),
+		version:        b.Version(),
+		sourceLocation: b.SourceLocation(),
+		localLocation:  b.LocalLocation(),
+		filesystem:     fs,
+		metadata:       b.metadata,
+	}
+}
+
+// Metadata returns a copy of the metadata of the project, if any was reported by
+// the source the project was found at.
+func (b *Backend) Metadata() *project.Metadata {
+	return b.metadata.Clone()
+}
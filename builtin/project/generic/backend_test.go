@@ -0,0 +1,109 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+func Test_Factory(t *testing.T) {
+	type input struct {
+		conf *project.BackendConfig
+	}
+
+	type want struct {
+		be  *Backend
+		err error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"config_with_no_name": {
+			input: input{
+				conf: &project.BackendConfig{
+					Name: "",
+				},
+			},
+			want: want{
+				be:  nil,
+				err: fmt.Errorf("name cannot be empty"),
+			},
+		},
+
+		"config_with_no_source_location": {
+			input: input{
+				conf: &project.BackendConfig{
+					Name: "name",
+				},
+			},
+			want: want{
+				be:  nil,
+				err: fmt.Errorf("source location cannot be empty"),
+			},
+		},
+
+		"return_backend": {
+			input: input{
+				conf: &project.BackendConfig{
+					Name:           "example.com/mod",
+					Version:        "v1.0.0",
+					SourceLocation: "example.com/mod@v1.0.0",
+				},
+			},
+			want: want{
+				be: &Backend{
+					name:           "example.com/mod",
+					version:        "v1.0.0",
+					sourceLocation: "example.com/mod@v1.0.0",
+				},
+				err: nil,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := Factory(context.TODO(), tt.input.conf)
+
+			compareBackend(t, tt.want.be, got)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Factory(): \n\tgotErr: '%v'\n\twantErr: '%v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
+
+func compareBackend(t *testing.T, want *Backend, got project.Backend) {
+	t.Helper()
+
+	if got == nil {
+		if want != nil {
+			t.Errorf("Factory() mismatched nil")
+		}
+		return
+	}
+
+	gotProjectBackend, ok := got.(*Backend)
+	if !ok {
+		t.Errorf("Factory() failed to type convert to generic.Backend")
+	}
+
+	if diff := cmp.Diff(want, gotProjectBackend, cmp.AllowUnexported(Backend{})); diff != "" {
+		t.Errorf("Factory() mismatched backend (-want +got):\n%s", diff)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"gopkg.in/src-d/go-billy.v4"
+
 	"github.com/mccurdyc/neighbor/sdk/project"
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
 )
@@ -18,11 +20,17 @@ func Factory(ctx context.Context, conf *project.BackendConfig) (project.Backend,
 		return nil, fmt.Errorf("source location cannot be empty")
 	}
 
+	metadata := conf.Metadata.Clone()
+	if metadata == nil {
+		metadata = &project.Metadata{}
+	}
+
 	return &Backend{
 		name:           conf.Name,
 		version:        conf.Version,
 		sourceLocation: conf.SourceLocation,
 		retrievalFunc:  conf.RetrievalFunc,
+		metadata:       metadata,
 	}, nil
 }
 
@@ -33,6 +41,8 @@ type Backend struct {
 	version        string
 	sourceLocation string
 	localLocation  string
+	metadata       *project.Metadata
+	filesystem     billy.Filesystem
 }
 
 // Name returns the name associated with a GitHub project.
@@ -70,5 +80,31 @@ func (b *Backend) SetLocalLocation(l string) project.Backend {
 		version:        b.Version(),
 		sourceLocation: b.SourceLocation(),
 		localLocation:  l,
+		metadata:       b.metadata,
+		filesystem:     b.Filesystem(),
 	}
 }
+
+// Filesystem returns the in-memory filesystem of the project, if it was retrieved
+// into memory.
+func (b *Backend) Filesystem() billy.Filesystem {
+	return b.filesystem
+}
+
+// SetFilesystem sets the in-memory filesystem.
+func (b *Backend) SetFilesystem(fs billy.Filesystem) project.Backend {
+	return &Backend{
+		name:           b.Name(),
+		retrievalFunc:  b.RetrievalFunc(),
+		version:        b.Version(),
+		sourceLocation: b.SourceLocation(),
+		localLocation:  b.LocalLocation(),
+		metadata:       b.metadata,
+		filesystem:     fs,
+	}
+}
+
+// Metadata returns a copy of the metadata of the GitHub repository as reported by GitHub.
+func (b *Backend) Metadata() *project.Metadata {
+	return b.metadata.Clone()
+}
@@ -6,10 +6,15 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+
 	"github.com/mccurdyc/neighbor/sdk/project"
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
 )
 
+func intPtr(n int) *int { return &n }
+
 func Test_Factory(t *testing.T) {
 	type input struct {
 		conf *project.BackendConfig
@@ -54,6 +59,7 @@ func Test_Factory(t *testing.T) {
 					Name:           "name",
 					Version:        "version",
 					SourceLocation: "sourcelocation",
+					Metadata:       &project.Metadata{Size: 2048},
 				},
 			},
 			want: want{
@@ -61,6 +67,35 @@ func Test_Factory(t *testing.T) {
 					name:           "name",
 					version:        "version",
 					sourceLocation: "sourcelocation",
+					metadata:       &project.Metadata{Size: 2048},
+				},
+				err: nil,
+			},
+		},
+
+		"return_backend_with_metadata": {
+			input: input{
+				conf: &project.BackendConfig{
+					Name:           "name",
+					SourceLocation: "sourcelocation",
+					Metadata: &project.Metadata{
+						Stars:      intPtr(42),
+						Language:   "Go",
+						Size:       4096,
+						Attributes: map[string]string{"description": "a project"},
+					},
+				},
+			},
+			want: want{
+				be: &Backend{
+					name:           "name",
+					sourceLocation: "sourcelocation",
+					metadata: &project.Metadata{
+						Stars:      intPtr(42),
+						Language:   "Go",
+						Size:       4096,
+						Attributes: map[string]string{"description": "a project"},
+					},
 				},
 				err: nil,
 			},
@@ -123,6 +158,10 @@ func compareBackend(t *testing.T, want *Backend, got project.Backend) {
 	if diff := cmp.Diff(want.localLocation, gotProjectBackend.localLocation, cmp.AllowUnexported()); diff != "" {
 		t.Errorf("Factory() mismatched localLocation(-want +got):\n%s", diff)
 	}
+
+	if diff := cmp.Diff(want.metadata, gotProjectBackend.metadata); diff != "" {
+		t.Errorf("Factory() mismatched metadata(-want +got):\n%s", diff)
+	}
 }
 
 func Test_Name(t *testing.T) {
@@ -362,3 +401,54 @@ func Test_SetLocalLocation(t *testing.T) {
 		})
 	}
 }
+
+func Test_SetFilesystem(t *testing.T) {
+	fs := memfs.New()
+
+	type input struct {
+		backend *Backend
+		fs      billy.Filesystem
+	}
+
+	type want struct {
+		value billy.Filesystem
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"value_not_set": {
+			input: input{
+				backend: &Backend{},
+				fs:      fs,
+			},
+			want: want{
+				value: fs,
+			},
+		},
+
+		"value_unset": {
+			input: input{
+				backend: &Backend{
+					filesystem: fs,
+				},
+				fs: nil,
+			},
+			want: want{
+				value: nil,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := tt.input.backend.SetFilesystem(tt.input.fs)
+
+			if got.Filesystem() != tt.want.value {
+				t.Errorf("SetFilesystem(%+v): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package corpus
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mccurdyc/neighbor/sdk/corpus"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// Factory is the factory function for creating the backend for a corpus archive
+// (see sdk/corpus) as a project retrieval method.
+//
+// The required "archive" config value is the path to the archive. The optional
+// "manifest" config value is the path to a separately published manifest, which
+// the projects are verified against instead of the manifest in the archive.
+func Factory(ctx context.Context, conf *retrieval.BackendConfig) (retrieval.Backend, error) {
+	archive := conf.Config["archive"]
+	if len(archive) == 0 {
+		return nil, fmt.Errorf("archive cannot be empty")
+	}
+
+	m, err := corpus.LoadManifest(archive, conf.Config["manifest"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		archive:  archive,
+		manifest: m,
+	}, nil
+}
+
+// Backend is the backend for project retrieval from a corpus archive.
+type Backend struct {
+	archive  string
+	manifest *corpus.Manifest
+}
+
+// Retrieve extracts the project named src from the archive to a local dir and
+// verifies its contents against the hash in the manifest.
+func (b *Backend) Retrieve(ctx context.Context, src string, dir string) error {
+	e, ok := b.manifest.Entry(src)
+	if !ok {
+		return fmt.Errorf("project '%s' not found in manifest", src)
+	}
+
+	return corpus.ExtractFile(b.archive, e, dir)
+}
@@ -0,0 +1,152 @@
+package corpus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mccurdyc/neighbor/sdk/corpus"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+func Test_Retrieve(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "neighbor-corpus-retrieval")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	src := filepath.Join(tmp, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %+v", err)
+	}
+
+	e, err := corpus.NewEntry("owner/name", "https://github.com/owner/name", "abc", time.Now(), src)
+	if err != nil {
+		t.Fatalf("failed to create entry: %+v", err)
+	}
+
+	archive := filepath.Join(tmp, "corpus.tar.gz")
+	m := &corpus.Manifest{Projects: []corpus.Entry{e}}
+	if err := corpus.WriteFile(archive, m); err != nil {
+		t.Fatalf("failed to write archive: %+v", err)
+	}
+
+	// a published manifest that disagrees with the archive.
+	m.Projects[0].Hash = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("failed to encode manifest: %+v", err)
+	}
+
+	tampered := filepath.Join(tmp, "manifest.json")
+	if err := ioutil.WriteFile(tampered, b, 0644); err != nil {
+		t.Fatalf("failed to write manifest: %+v", err)
+	}
+
+	type input struct {
+		conf *retrieval.BackendConfig
+		src  string
+	}
+
+	type want struct {
+		factoryErr error
+		err        error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"retrieved": {
+			input: input{
+				conf: &retrieval.BackendConfig{Config: map[string]string{"archive": archive}},
+				src:  "https://github.com/owner/name",
+			},
+			want: want{},
+		},
+
+		"retrieved_by_name": {
+			input: input{
+				conf: &retrieval.BackendConfig{Config: map[string]string{"archive": archive}},
+				src:  "owner/name",
+			},
+			want: want{},
+		},
+
+		"not_found": {
+			input: input{
+				conf: &retrieval.BackendConfig{Config: map[string]string{"archive": archive}},
+				src:  "owner/other",
+			},
+			want: want{
+				err: fmt.Errorf("project 'owner/other' not found in manifest"),
+			},
+		},
+
+		"hash_mismatch": {
+			input: input{
+				conf: &retrieval.BackendConfig{Config: map[string]string{"archive": archive, "manifest": tampered}},
+				src:  "owner/name",
+			},
+			want: want{
+				err: corpus.ErrHashMismatch,
+			},
+		},
+
+		"missing_archive": {
+			input: input{
+				conf: &retrieval.BackendConfig{},
+			},
+			want: want{
+				factoryErr: fmt.Errorf("archive cannot be empty"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error() || errors.Is(x, y)
+			}
+
+			b, gotErr := Factory(context.TODO(), tt.input.conf)
+			if ok := errorCmp(gotErr, tt.want.factoryErr); !ok {
+				t.Fatalf("Factory() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.factoryErr)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			dir := filepath.Join(tmp, "dst", name)
+			gotErr = b.Retrieve(context.TODO(), tt.input.src, dir)
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("Retrieve() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "main.go")); err != nil {
+				t.Errorf("Retrieve() missing file: %+v", err)
+			}
+		})
+	}
+}
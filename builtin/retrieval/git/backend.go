@@ -2,19 +2,54 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	nethttp "net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 
+	"golang.org/x/oauth2"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
 	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/cache"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/storage/filesystem"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
 
+	"github.com/mccurdyc/neighbor/builtin/internal/githubapp"
+	"github.com/mccurdyc/neighbor/sdk/auth"
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
 )
 
 // Factory is the factory function for creating the backend for Git as a project
 // retrieval method.
+//
+// Submodules are initialized when the "submodules" config value is true, up to
+// "submodule_depth" levels deep (defaults to 10). Git LFS pointer files are
+// resolved when the "lfs" config value is true. The optional "max_files" and
+// "max_bytes" config values limit the files and bytes written by a clone.
+//
+// Besides "basic" and "token", the "github_app" auth method authenticates as an
+// installation of a GitHub App (see githubapp.FromConfig for its config values).
+// The optional "base_url" config value is the API url of a GitHub Enterprise
+// Server instance that issues the installation tokens. The "credentials" auth
+// method uses the credential that conf.Credentials has for the host of each
+// project, if any.
 func Factory(ctx context.Context, conf *retrieval.BackendConfig) (retrieval.Backend, error) {
+	var credentials auth.Provider
+	if strings.EqualFold(conf.AuthMethod, "credentials") {
+		if conf.Credentials == nil {
+			return nil, fmt.Errorf("credentials provider required for credentials auth")
+		}
+		credentials = conf.Credentials
+	}
+
 	var auth transport.AuthMethod
 
 	if strings.EqualFold(conf.AuthMethod, "basic") {
@@ -46,31 +81,392 @@ func Factory(ctx context.Context, conf *retrieval.BackendConfig) (retrieval.Back
 		}
 	}
 
+	var ts oauth2.TokenSource
+	if strings.EqualFold(conf.AuthMethod, "github_app") {
+		var err error
+		if ts, err = githubapp.FromConfig(conf.Config, conf.Config["base_url"], nil); err != nil {
+			return nil, err
+		}
+	}
+
+	submoduleDepth, err := parseSubmoduleDepth(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	var lfs bool
+	if v := conf.Config["lfs"]; len(v) != 0 {
+		lfs, err = strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lfs value '%s'", v)
+		}
+	}
+
+	var limits retrieval.Limits
+	if limits.MaxFiles, err = parseLimit(conf.Config, "max_files"); err != nil {
+		return nil, err
+	}
+
+	if limits.MaxBytes, err = parseLimit(conf.Config, "max_bytes"); err != nil {
+		return nil, err
+	}
+
 	return &Backend{
-		auth: auth,
+		auth:           auth,
+		tokenSource:    ts,
+		credentials:    credentials,
+		submoduleDepth: submoduleDepth,
+		lfs:            lfs,
+		limits:         limits,
+		httpClient:     nethttp.DefaultClient,
 	}, nil
 }
 
+func parseLimit(conf map[string]string, key string) (int64, error) {
+	v := conf[key]
+	if len(v) == 0 {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative integer", key)
+	}
+
+	return n, nil
+}
+
+func parseSubmoduleDepth(conf map[string]string) (git.SubmoduleRescursivity, error) {
+	v := conf["submodules"]
+	if len(v) == 0 {
+		return git.NoRecurseSubmodules, nil
+	}
+
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return git.NoRecurseSubmodules, fmt.Errorf("invalid submodules value '%s'", v)
+	}
+
+	if !enabled {
+		return git.NoRecurseSubmodules, nil
+	}
+
+	d := conf["submodule_depth"]
+	if len(d) == 0 {
+		return git.DefaultSubmoduleRecursionDepth, nil
+	}
+
+	depth, err := strconv.Atoi(d)
+	if err != nil || depth < 1 {
+		return git.NoRecurseSubmodules, fmt.Errorf("submodule_depth must be a positive integer")
+	}
+
+	return git.SubmoduleRescursivity(depth), nil
+}
+
 // Backend is the backend for project retrieval using Git.
 type Backend struct {
 	auth transport.AuthMethod
+	// tokenSource issues the installation tokens used instead of auth when
+	// authenticating as a GitHub App.
+	tokenSource oauth2.TokenSource
+	// credentials provides the credentials used instead of auth for each host.
+	credentials auth.Provider
+	// submoduleDepth is how many levels of submodules are initialized. Zero
+	// disables submodule initialization.
+	submoduleDepth git.SubmoduleRescursivity
+	lfs            bool
+	// limits are the file and byte limits enforced while cloning.
+	limits     retrieval.Limits
+	httpClient *nethttp.Client
 }
 
 // Retrieve clones a remote Git repository specified by src to a local dir.
+//
+// If the clone succeeds, but submodules or LFS objects could not be retrieved,
+// a *SubmoduleError or *LFSError is returned and dir is left in place. If the
+// clone or its submodules exceed a limit, a *retrieval.LimitError is returned
+// and dir is removed.
 func (b *Backend) Retrieve(ctx context.Context, src string, dir string) error {
+	opts, err := b.cloneOptions(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	if b.limits.MaxFiles == 0 && b.limits.MaxBytes == 0 {
+		repo, err := git.PlainCloneContext(ctx, dir, false, opts)
+		if err != nil {
+			return err
+		}
+
+		return b.postClone(ctx, repo, src, dir, opts.Auth, nil)
+	}
+
+	// only clean up what was written by the clone, never a pre-existing directory.
+	_, statErr := os.Stat(dir)
+	cleanUp := os.IsNotExist(statErr)
+
+	counter := &limitCounter{limits: b.limits}
+	repo, err := cloneWithLimits(ctx, dir, opts, counter)
+	if err != nil {
+		if cleanUp {
+			os.RemoveAll(dir)
+		}
+
+		if limitErr := counter.Err(); limitErr != nil {
+			return limitErr
+		}
+		return err
+	}
+
+	err = b.postClone(ctx, repo, src, dir, opts.Auth, counter)
+	if limitErr := counter.Err(); limitErr != nil {
+		if cleanUp {
+			os.RemoveAll(dir)
+		}
+		return limitErr
+	}
+
+	return err
+}
+
+// RetrieveFilesystem clones a remote Git repository specified by src into memory
+// and returns its worktree. Nothing is written to disk, which makes this suitable
+// for read-only analyses performed in-process.
+//
+// The file and byte limits only apply to the worktree and LFS objects are not
+// supported.
+func (b *Backend) RetrieveFilesystem(ctx context.Context, src string) (billy.Filesystem, error) {
+	if b.lfs {
+		return nil, fmt.Errorf("LFS is not supported for in-memory retrieval")
+	}
+
+	opts, err := b.cloneOptions(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	counter := &limitCounter{limits: b.limits}
+	var wt billy.Filesystem = memfs.New()
+	if b.limits.MaxFiles != 0 || b.limits.MaxBytes != 0 {
+		wt = &limitFilesystem{Filesystem: wt, counter: counter, countFiles: true}
+	}
+
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), wt, opts)
+	if err == nil && b.submoduleDepth != git.NoRecurseSubmodules {
+		err = b.updateSubmodules(ctx, repo, src, opts.Auth, b.submoduleDepth)
+	}
+
+	if limitErr := counter.Err(); limitErr != nil {
+		return nil, limitErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return wt, nil
+}
+
+func (b *Backend) cloneOptions(ctx context.Context, src string) (*git.CloneOptions, error) {
 	opts := git.CloneOptions{
 		URL: src,
 	}
 
-	if b.auth != nil {
-		opts.Auth = b.auth
+	auth, err := b.authMethod(ctx, src)
+	if err != nil {
+		return nil, err
 	}
 
-	err := opts.Validate()
+	if auth != nil {
+		opts.Auth = auth
+	}
+
+	return &opts, opts.Validate()
+}
+
+// authMethod returns the auth method for a single retrieval of src. Installation
+// tokens expire and credentials depend on the host of src, so a new auth method
+// is created for every retrieval when authenticating as a GitHub App or with a
+// credentials provider.
+func (b *Backend) authMethod(ctx context.Context, src string) (transport.AuthMethod, error) {
+	if b.credentials != nil {
+		return credentialAuth(ctx, b.credentials, src)
+	}
+
+	if b.tokenSource == nil {
+		return b.auth, nil
+	}
+
+	tok, err := b.tokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installation token: %+v", err)
+	}
+
+	return &http.BasicAuth{
+		Username: "x-access-token",
+		Password: tok.AccessToken,
+	}, nil
+}
+
+func cloneWithLimits(ctx context.Context, dir string, opts *git.CloneOptions, counter *limitCounter) (*git.Repository, error) {
+	root := osfs.New(dir)
+	dot, err := root.Chroot(git.GitDirName)
+	if err != nil {
+		return nil, err
+	}
+
+	s := filesystem.NewStorage(&limitFilesystem{Filesystem: dot, counter: counter}, cache.NewObjectLRUDefault())
+	wt := &limitFilesystem{Filesystem: root, counter: counter, countFiles: true}
+
+	return git.CloneContext(ctx, s, wt, opts)
+}
+
+// credentialAuth returns the auth method for the credential that p has for the
+// host of src or nil if it has none.
+func credentialAuth(ctx context.Context, p auth.Provider, src string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(src)
+	if err != nil {
+		return nil, err
+	}
+
+	cred, err := p.Credential(ctx, ep.Host)
+	if errors.Is(err, auth.ErrNoCredential) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential for '%s': %+v", ep.Host, err)
+	}
+
+	username := cred.Username
+	if cred.Token() {
+		username = "null" // this can't be an empty string
+	}
+
+	return &http.BasicAuth{
+		Username: username,
+		Password: cred.Password,
+	}, nil
+}
+
+// postClone initializes submodules and resolves LFS objects, if enabled. LFS
+// objects are charged to counter, if not nil, since they are written directly to
+// dir rather than through the clone's filesystem.
+func (b *Backend) postClone(ctx context.Context, repo *git.Repository, src string, dir string, auth transport.AuthMethod, counter *limitCounter) error {
+	if b.submoduleDepth != git.NoRecurseSubmodules {
+		if err := b.updateSubmodules(ctx, repo, src, auth, b.submoduleDepth); err != nil {
+			return err
+		}
+	}
+
+	if b.lfs {
+		remotes, err := lfsRemotes(repo, src)
+		if err != nil {
+			return err
+		}
+
+		return resolveLFS(ctx, b.httpClient, auth, remotes, dir, counter)
+	}
+
+	return nil
+}
+
+// SubmoduleError is returned when one or more submodules of a project could
+// not be initialized.
+type SubmoduleError struct {
+	// Failures maps the path of a submodule to the reason it failed.
+	Failures map[string]error
+}
+
+func (e *SubmoduleError) Error() string {
+	return fmt.Sprintf("failed to initialize %d submodule(s): %s", len(e.Failures), joinFailures(e.Failures))
+}
+
+// updateSubmodules initializes the submodules of repo, whose remote url is src,
+// recursively up to depth levels. auth is only sent to submodules on the host of
+// src, since any repository can point its submodules at an arbitrary host.
+func (b *Backend) updateSubmodules(ctx context.Context, repo *git.Repository, src string, auth transport.AuthMethod, depth git.SubmoduleRescursivity) error {
+	wt, err := repo.Worktree()
 	if err != nil {
 		return err
 	}
 
-	_, err = git.PlainClone(dir, false, &opts)
-	return err
+	subs, err := wt.Submodules()
+	if err != nil {
+		return err
+	}
+
+	failures := make(map[string]error)
+	for _, s := range subs {
+		u := submoduleURL(src, s.Config().URL)
+
+		subAuth, err := b.submoduleAuth(ctx, src, u, auth)
+		if err == nil {
+			err = s.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+				Init:              true,
+				RecurseSubmodules: git.NoRecurseSubmodules,
+				Auth:              subAuth,
+			})
+		}
+		if err != nil {
+			failures[s.Config().Path] = err
+			continue
+		}
+
+		if depth <= 1 {
+			continue
+		}
+
+		sub, err := s.Repository()
+		if err != nil {
+			failures[s.Config().Path] = err
+			continue
+		}
+
+		err = b.updateSubmodules(ctx, sub, u, subAuth, depth-1)
+		if subErr, ok := err.(*SubmoduleError); ok {
+			for p, f := range subErr.Failures {
+				failures[path.Join(s.Config().Path, p)] = f
+			}
+		} else if err != nil {
+			failures[s.Config().Path] = err
+		}
+	}
+
+	if len(failures) != 0 {
+		return &SubmoduleError{Failures: failures}
+	}
+
+	return nil
+}
+
+// submoduleAuth returns the auth method for the submodule at u of the project at
+// parent. The auth of the project is reused for the same host, otherwise the
+// credentials provider is asked for the host of u, if there is one.
+func (b *Backend) submoduleAuth(ctx context.Context, parent string, u string, auth transport.AuthMethod) (transport.AuthMethod, error) {
+	if sameHost(parent, u) {
+		return auth, nil
+	}
+
+	if b.credentials != nil {
+		return credentialAuth(ctx, b.credentials, u)
+	}
+
+	return nil, nil
+}
+
+// joinFailures formats failures deterministically, sorted by path.
+func joinFailures(failures map[string]error) string {
+	paths := make([]string, 0, len(failures))
+	for p := range failures {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	msgs := make([]string, 0, len(paths))
+	for _, p := range paths {
+		msgs = append(msgs, fmt.Sprintf("%s: %+v", p, failures[p]))
+	}
+
+	return strings.Join(msgs, "; ")
 }
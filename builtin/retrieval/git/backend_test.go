@@ -3,10 +3,18 @@ package git
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/mccurdyc/neighbor/sdk/auth"
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
+	"golang.org/x/oauth2"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 )
 
@@ -106,6 +114,97 @@ func Test_Factory(t *testing.T) {
 				err: fmt.Errorf("token required for token auth"),
 			},
 		},
+
+		"config_with_github_app_missing_app_id": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					AuthMethod: "github_app",
+					Config:     map[string]string{"installation_id": "42", "private_key": "key"},
+				},
+			},
+			want: want{
+				be:  nil,
+				err: fmt.Errorf("app_id required for github_app auth"),
+			},
+		},
+
+		"config_with_submodules_default_depth": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"submodules": "true"},
+				},
+			},
+			want: want{
+				be: &Backend{
+					submoduleDepth: git.DefaultSubmoduleRecursionDepth,
+				},
+				err: nil,
+			},
+		},
+
+		"config_with_submodules_depth": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"submodules": "true", "submodule_depth": "2"},
+				},
+			},
+			want: want{
+				be: &Backend{
+					submoduleDepth: 2,
+				},
+				err: nil,
+			},
+		},
+
+		"config_with_submodules_disabled": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"submodules": "false", "submodule_depth": "2"},
+				},
+			},
+			want: want{
+				be:  &Backend{},
+				err: nil,
+			},
+		},
+
+		"config_with_invalid_submodule_depth": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"submodules": "true", "submodule_depth": "0"},
+				},
+			},
+			want: want{
+				be:  nil,
+				err: fmt.Errorf("submodule_depth must be a positive integer"),
+			},
+		},
+
+		"config_with_lfs": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"lfs": "true"},
+				},
+			},
+			want: want{
+				be: &Backend{
+					lfs: true,
+				},
+				err: nil,
+			},
+		},
+
+		"config_with_invalid_lfs": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"lfs": "maybe"},
+				},
+			},
+			want: want{
+				be:  nil,
+				err: fmt.Errorf("invalid lfs value 'maybe'"),
+			},
+		},
 	}
 
 	for name, tt := range tests {
@@ -148,6 +247,14 @@ func compareBackend(t *testing.T, want *Backend, got retrieval.Backend) {
 	if diff := cmp.Diff(want.auth, gotGitBackend.auth, cmp.AllowUnexported()); diff != "" {
 		t.Errorf("Factory() mismatched auth (-want +got):\n%s", diff)
 	}
+
+	if diff := cmp.Diff(want.submoduleDepth, gotGitBackend.submoduleDepth); diff != "" {
+		t.Errorf("Factory() mismatched submodule depth (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(want.lfs, gotGitBackend.lfs); diff != "" {
+		t.Errorf("Factory() mismatched lfs (-want +got):\n%s", diff)
+	}
 }
 
 func Test_Retrieval(t *testing.T) {
@@ -155,3 +262,182 @@ func Test_Retrieval(t *testing.T) {
 	// test flag and skipped by default.
 	t.Skip()
 }
+
+func Test_authMethod(t *testing.T) {
+	type want struct {
+		auth transport.AuthMethod
+	}
+
+	var tests = map[string]struct {
+		input *Backend
+		want  want
+	}{
+		"static_auth": {
+			input: &Backend{
+				auth: &http.BasicAuth{Username: "null", Password: "token123"},
+			},
+			want: want{
+				auth: &http.BasicAuth{Username: "null", Password: "token123"},
+			},
+		},
+
+		"credentials_token": {
+			input: &Backend{
+				credentials: hostCredentials{"github.com": {Password: "token123"}},
+			},
+			want: want{
+				auth: &http.BasicAuth{Username: "null", Password: "token123"},
+			},
+		},
+
+		"credentials_username_password": {
+			input: &Backend{
+				credentials: hostCredentials{"github.com": {Username: "octocat", Password: "password123"}},
+			},
+			want: want{
+				auth: &http.BasicAuth{Username: "octocat", Password: "password123"},
+			},
+		},
+
+		"credentials_none": {
+			input: &Backend{
+				credentials: auth.Chain{},
+			},
+			want: want{
+				auth: nil,
+			},
+		},
+
+		"github_app": {
+			input: &Backend{
+				tokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "installation123"}),
+			},
+			want: want{
+				auth: &http.BasicAuth{Username: "x-access-token", Password: "installation123"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, err := tt.input.authMethod(context.TODO(), "https://github.com/owner/name.git")
+			if err != nil {
+				t.Fatalf("authMethod() unexpected error: %+v", err)
+			}
+
+			if diff := cmp.Diff(tt.want.auth, got); diff != "" {
+				t.Errorf("authMethod() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// hostCredentials is a credential provider with a credential per host.
+type hostCredentials map[string]*auth.Credential
+
+func (h hostCredentials) Credential(ctx context.Context, host string) (*auth.Credential, error) {
+	cred, ok := h[host]
+	if !ok {
+		return nil, auth.ErrNoCredential
+	}
+
+	return cred, nil
+}
+
+// recordingTransport records the auth of each session and fails it.
+type recordingTransport struct {
+	auth map[string]transport.AuthMethod
+}
+
+func (r *recordingTransport) NewUploadPackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.UploadPackSession, error) {
+	r.auth[ep.Host] = auth
+	return nil, fmt.Errorf("%s is unreachable", ep.Host)
+}
+
+func (r *recordingTransport) NewReceivePackSession(ep *transport.Endpoint, auth transport.AuthMethod) (transport.ReceivePackSession, error) {
+	r.auth[ep.Host] = auth
+	return nil, fmt.Errorf("%s is unreachable", ep.Host)
+}
+
+func Test_Retrieve_submoduleAuth(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	sub := newLocalRepository(t, map[string]string{"sub.txt": "sub"})
+	defer os.RemoveAll(sub)
+
+	src := newLocalRepository(t, map[string]string{"a.txt": "a"})
+	defer os.RemoveAll(src)
+
+	// the submodule is added from disk, but points at another host afterwards.
+	for _, args := range [][]string{
+		{"-c", "protocol.file.allow=always", "submodule", "add", "-q", sub, "lib"},
+		{"config", "-f", ".gitmodules", "submodule.lib.url", "https://evil.example.com/lib.git"},
+		{"add", ".gitmodules"},
+		{"-c", "user.name=neighbor", "-c", "user.email=neighbor@example.com", "commit", "-q", "-m", "add submodule"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", src}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("failed to run git %v: %+v: %s", args, err, out)
+		}
+	}
+
+	var tests = map[string]struct {
+		input *Backend
+		want  transport.AuthMethod
+	}{
+		"static_auth": {
+			input: &Backend{
+				auth: &http.BasicAuth{Username: "null", Password: "token123"},
+			},
+			want: nil,
+		},
+
+		"credentials": {
+			input: &Backend{
+				credentials: hostCredentials{"evil.example.com": {Password: "evil123"}},
+			},
+			want: &http.BasicAuth{Username: "null", Password: "evil123"},
+		},
+
+		"credentials_none": {
+			input: &Backend{
+				credentials: hostCredentials{"github.com": {Password: "token123"}},
+			},
+			want: nil,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			rt := &recordingTransport{auth: make(map[string]transport.AuthMethod)}
+			client.InstallProtocol("https", rt)
+			defer client.InstallProtocol("https", http.DefaultClient)
+
+			dir, err := ioutil.TempDir("", "neighbor-git-dst")
+			if err != nil {
+				t.Fatalf("failed to create directory: %+v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			tt.input.submoduleDepth = git.DefaultSubmoduleRecursionDepth
+
+			err = tt.input.Retrieve(context.TODO(), src, dir)
+			if _, ok := err.(*SubmoduleError); !ok {
+				t.Fatalf("Retrieve() \n\tgotErr: '%+v'\n\twantErr: '*SubmoduleError'", err)
+			}
+
+			got, ok := rt.auth["evil.example.com"]
+			if !ok {
+				t.Fatalf("Retrieve() did not fetch the submodule")
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Retrieve() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
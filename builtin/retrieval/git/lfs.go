@@ -0,0 +1,425 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+// https://github.com/git-lfs/git-lfs/blob/master/docs/spec.md
+const (
+	lfsPointerPrefix = "version https://git-lfs.github.com/spec/v1"
+	// lfsMaxPointerSize is the maximum size of a pointer file.
+	lfsMaxPointerSize = 1024
+	lfsMediaType      = "application/vnd.git-lfs+json"
+	// lfsBatchSize is the number of objects requested per batch API call.
+	lfsBatchSize = 100
+)
+
+// LFSError is returned when one or more Git LFS pointer files of a project could
+// not be resolved.
+type LFSError struct {
+	// Failures maps the path of a pointer file to the reason it failed.
+	Failures map[string]error
+}
+
+func (e *LFSError) Error() string {
+	return fmt.Sprintf("failed to resolve %d LFS object(s): %s", len(e.Failures), joinFailures(e.Failures))
+}
+
+type lfsPointer struct {
+	path string
+	oid  string
+	size int64
+}
+
+type lfsObject struct {
+	OID     string `json:"oid"`
+	Size    int64  `json:"size"`
+	Actions struct {
+		Download *struct {
+			Href   string            `json:"href"`
+			Header map[string]string `json:"header"`
+		} `json:"download"`
+	} `json:"actions"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type lfsBatchRequest struct {
+	Operation string      `json:"operation"`
+	Transfers []string    `json:"transfers"`
+	Objects   []lfsObject `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsObject `json:"objects"`
+}
+
+// resolveLFS replaces the LFS pointer files in dir with the objects they point to
+// using the LFS batch API of the repository that each pointer file belongs to.
+// remotes maps the path, relative to dir, of the top-level repository (i.e., "")
+// and each of its submodules to its remote url. Credentials are only sent to the
+// host of the top-level repository.
+//
+// The size of each object is charged to counter, if not nil, before it is
+// downloaded and the first exceeded limit is returned as is.
+func resolveLFS(ctx context.Context, c *nethttp.Client, auth transport.AuthMethod, remotes map[string]string, dir string, counter *limitCounter) error {
+	pointers, err := findLFSPointers(dir)
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[string][]lfsPointer)
+	for _, p := range pointers {
+		remote := remotes[lfsRepository(remotes, p.path)]
+		groups[remote] = append(groups[remote], p)
+	}
+
+	failures := make(map[string]error)
+	for remote, pointers := range groups {
+		a := auth
+		if remote != remotes[""] && !sameHost(remote, remotes[""]) {
+			a = nil
+		}
+
+		for start := 0; start < len(pointers); start += lfsBatchSize {
+			end := start + lfsBatchSize
+			if end > len(pointers) {
+				end = len(pointers)
+			}
+
+			batch := pointers[start:end]
+			objects, err := lfsBatch(ctx, c, a, lfsEndpoint(remote), batch)
+			if err != nil {
+				for _, p := range batch {
+					failures[p.path] = err
+				}
+				continue
+			}
+
+			for _, p := range batch {
+				if counter != nil {
+					if err := counter.addBytes(p.size); err != nil {
+						return err
+					}
+				}
+
+				if err := downloadLFSObject(ctx, c, p, objects[p.oid], filepath.Join(dir, p.path)); err != nil {
+					failures[p.path] = err
+				}
+			}
+		}
+	}
+
+	if len(failures) != 0 {
+		return &LFSError{Failures: failures}
+	}
+
+	return nil
+}
+
+// lfsRepository returns the path of the innermost repository of remotes that
+// contains the file at p.
+func lfsRepository(remotes map[string]string, p string) string {
+	paths := make([]string, 0, len(remotes))
+	for r := range remotes {
+		paths = append(paths, r)
+	}
+	// longer paths are nested deeper.
+	sort.Slice(paths, func(i, j int) bool { return len(paths[i]) > len(paths[j]) })
+
+	for _, r := range paths {
+		if len(r) != 0 && strings.HasPrefix(p, r+string(filepath.Separator)) {
+			return r
+		}
+	}
+
+	return ""
+}
+
+// lfsRemotes returns the remote url of repo, which is src, and of each of its
+// initialized submodules, recursively, keyed by their path relative to repo.
+func lfsRemotes(repo *git.Repository, src string) (map[string]string, error) {
+	remotes := make(map[string]string)
+	return remotes, addSubmoduleRemotes(remotes, repo, src, "")
+}
+
+func addSubmoduleRemotes(remotes map[string]string, repo *git.Repository, src string, dir string) error {
+	remotes[dir] = src
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	subs, err := wt.Submodules()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range subs {
+		sub, err := s.Repository()
+		if err == git.ErrSubmoduleNotInitialized {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		p := filepath.Join(dir, filepath.FromSlash(s.Config().Path))
+		if err := addSubmoduleRemotes(remotes, sub, submoduleURL(src, s.Config().URL), p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// submoduleURL returns the url of a submodule, which may be relative to the url
+// of its superproject (e.g., ../other.git).
+func submoduleURL(parent string, u string) string {
+	if !strings.HasPrefix(u, "./") && !strings.HasPrefix(u, "../") {
+		return u
+	}
+
+	// the url of the superproject is the directory that u is relative to.
+	base, err := url.Parse(strings.TrimSuffix(parent, "/") + "/")
+	if err == nil && len(base.Scheme) != 0 {
+		return base.ResolveReference(&url.URL{Path: u}).String()
+	}
+
+	// scp-like urls, e.g., git@github.com:owner/repo.git.
+	if i := strings.Index(parent, ":"); i > 0 {
+		return parent[:i+1] + path.Join(parent[i+1:], u)
+	}
+
+	return path.Join(parent, u)
+}
+
+// sameHost returns whether the urls a and b have the same host. Local paths have
+// no host, so they are only the same host as other local paths.
+func sameHost(a string, b string) bool {
+	ea, err := transport.NewEndpoint(a)
+	if err != nil {
+		return false
+	}
+
+	eb, err := transport.NewEndpoint(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(ea.Host, eb.Host)
+}
+
+// lfsEndpoint returns the LFS server url for a Git remote url.
+func lfsEndpoint(src string) string {
+	src = strings.TrimSuffix(src, "/")
+	if !strings.HasSuffix(src, ".git") {
+		src += ".git"
+	}
+
+	return src + "/info/lfs"
+}
+
+func findLFSPointers(dir string) ([]lfsPointer, error) {
+	var pointers []lfsPointer
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if !info.Mode().IsRegular() || info.Size() > lfsMaxPointerSize {
+			return nil
+		}
+
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+
+		ptr, ok := parseLFSPointer(b)
+		if !ok {
+			return nil
+		}
+
+		ptr.path, err = filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		pointers = append(pointers, ptr)
+		return nil
+	})
+
+	return pointers, err
+}
+
+func parseLFSPointer(b []byte) (lfsPointer, bool) {
+	var ptr lfsPointer
+
+	if !bytes.HasPrefix(b, []byte(lfsPointerPrefix)) {
+		return ptr, false
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		fields := strings.SplitN(s.Text(), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "oid":
+			ptr.oid = strings.TrimPrefix(fields[1], "sha256:")
+		case "size":
+			size, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return ptr, false
+			}
+			ptr.size = size
+		}
+	}
+
+	return ptr, len(ptr.oid) != 0
+}
+
+func lfsBatch(ctx context.Context, c *nethttp.Client, auth transport.AuthMethod, endpoint string, pointers []lfsPointer) (map[string]lfsObject, error) {
+	body := lfsBatchRequest{
+		Operation: "download",
+		Transfers: []string{"basic"},
+	}
+
+	for _, p := range pointers {
+		body.Objects = append(body.Objects, lfsObject{OID: p.oid, Size: p.size})
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := nethttp.NewRequest(nethttp.MethodPost, endpoint+"/objects/batch", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", lfsMediaType)
+	req.Header.Set("Content-Type", lfsMediaType)
+	if a, ok := auth.(*http.BasicAuth); ok && a != nil {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != nethttp.StatusOK {
+		return nil, fmt.Errorf("unexpected response from LFS batch API: %s", resp.Status)
+	}
+
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return nil, fmt.Errorf("failed to decode LFS batch response: %+v", err)
+	}
+
+	objects := make(map[string]lfsObject, len(batch.Objects))
+	for _, o := range batch.Objects {
+		objects[o.OID] = o
+	}
+
+	return objects, nil
+}
+
+func downloadLFSObject(ctx context.Context, c *nethttp.Client, p lfsPointer, o lfsObject, dst string) error {
+	if o.Error != nil {
+		return fmt.Errorf("LFS server error %d: %s", o.Error.Code, o.Error.Message)
+	}
+
+	if o.Actions.Download == nil {
+		return fmt.Errorf("missing download action for object %s", p.oid)
+	}
+
+	req, err := nethttp.NewRequest(nethttp.MethodGet, o.Actions.Download.Href, nil)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range o.Actions.Download.Header {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != nethttp.StatusOK {
+		return fmt.Errorf("unexpected response downloading object %s: %s", p.oid, resp.Status)
+	}
+
+	// write to a temporary file first so that the pointer file is only replaced
+	// with a verified object.
+	tmp, err := ioutil.TempFile(filepath.Dir(dst), ".lfs-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if n != p.size {
+		return fmt.Errorf("size mismatch for object %s: got %d, want %d", p.oid, n, p.size)
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != p.oid {
+		return fmt.Errorf("checksum mismatch for object %s: got %s", p.oid, got)
+	}
+
+	info, err := os.Stat(dst)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
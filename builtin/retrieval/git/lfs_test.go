@@ -0,0 +1,361 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+	"gopkg.in/src-d/go-git.v4"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+func lfsPointerFile(contents string) (string, string) {
+	sum := sha256.Sum256([]byte(contents))
+	oid := hex.EncodeToString(sum[:])
+
+	return oid, fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsPointerPrefix, oid, len(contents))
+}
+
+func Test_resolveLFS(t *testing.T) {
+	// objects maps the name of each repository to the objects of its LFS server.
+	objects := map[string]map[string]string{
+		"repo": {},
+		"sub":  {},
+	}
+
+	goodOID, goodPointer := lfsPointerFile("large binary contents")
+	objects["repo"][goodOID] = "large binary contents"
+
+	corruptOID, corruptPointer := lfsPointerFile("expected contents")
+	objects["repo"][corruptOID] = "corrupted contents"
+
+	_, missingPointer := lfsPointerFile("missing contents")
+
+	// the object of the submodule is only on the LFS server of the submodule.
+	subOID, subPointer := lfsPointerFile("submodule contents")
+	objects["sub"][subOID] = "submodule contents"
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/owner/repo.git/info/lfs/objects/batch", "/owner/sub.git/info/lfs/objects/batch":
+			repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/owner/"), ".git/info/lfs/objects/batch")
+
+			if u, p, ok := r.BasicAuth(); !ok || u != "null" || p != "token123" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			var req lfsBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			var resp lfsBatchResponse
+			for _, o := range req.Objects {
+				o := o
+				if _, ok := objects[repo][o.OID]; !ok {
+					o.Error = &struct {
+						Code    int    `json:"code"`
+						Message string `json:"message"`
+					}{Code: 404, Message: "Object does not exist"}
+				} else {
+					o.Actions.Download = &struct {
+						Href   string            `json:"href"`
+						Header map[string]string `json:"header"`
+					}{Href: srv.URL + "/objects/" + repo + "/" + o.OID}
+				}
+				resp.Objects = append(resp.Objects, o)
+			}
+
+			w.Header().Set("Content-Type", lfsMediaType)
+			json.NewEncoder(w).Encode(resp)
+		default:
+			contents, ok := objects[filepath.Base(filepath.Dir(r.URL.Path))][filepath.Base(r.URL.Path)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(contents))
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "neighbor-lfs")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"good.bin":    goodPointer,
+		"corrupt.bin": corruptPointer,
+		"missing.bin": missingPointer,
+		"README.md":   "not a pointer",
+		"sub/sub.bin": subPointer,
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write file: %+v", err)
+		}
+	}
+
+	auth := &githttp.BasicAuth{Username: "null", Password: "token123"}
+	remotes := map[string]string{
+		"":    srv.URL + "/owner/repo",
+		"sub": srv.URL + "/owner/sub",
+	}
+	gotErr := resolveLFS(context.TODO(), srv.Client(), auth, remotes, dir, nil)
+
+	lfsErr, ok := gotErr.(*LFSError)
+	if !ok {
+		t.Fatalf("resolveLFS() \n\tgotErr: '%+v'\n\twantErr: *LFSError", gotErr)
+	}
+
+	for _, name := range []string{"corrupt.bin", "missing.bin"} {
+		if _, ok := lfsErr.Failures[name]; !ok {
+			t.Errorf("resolveLFS() missing failure for '%s'", name)
+		}
+	}
+
+	if len(lfsErr.Failures) != 2 {
+		t.Errorf("resolveLFS() unexpected failures: %+v", lfsErr.Failures)
+	}
+
+	want := map[string]string{
+		"good.bin":    "large binary contents",
+		"corrupt.bin": corruptPointer,
+		"missing.bin": missingPointer,
+		"README.md":   "not a pointer",
+		"sub/sub.bin": "submodule contents",
+	}
+
+	for name, contents := range want {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("resolveLFS() failed to read '%s': %+v", name, err)
+			continue
+		}
+
+		if string(got) != contents {
+			t.Errorf("resolveLFS() mismatched contents of '%s': \n\tgot: '%s'\n\twant: '%s'", name, got, contents)
+		}
+	}
+}
+
+func Test_resolveLFS_limits(t *testing.T) {
+	contents := strings.Repeat("x", 4096)
+	oid, pointer := lfsPointerFile(contents)
+
+	var downloads int
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/owner/repo.git/info/lfs/objects/batch" {
+			downloads++
+			w.Write([]byte(contents))
+			return
+		}
+
+		o := lfsObject{OID: oid, Size: int64(len(contents))}
+		o.Actions.Download = &struct {
+			Href   string            `json:"href"`
+			Header map[string]string `json:"header"`
+		}{Href: srv.URL + "/objects/" + oid}
+
+		w.Header().Set("Content-Type", lfsMediaType)
+		json.NewEncoder(w).Encode(lfsBatchResponse{Objects: []lfsObject{o}})
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "neighbor-lfs")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.bin"), []byte(pointer), 0644); err != nil {
+		t.Fatalf("failed to write file: %+v", err)
+	}
+
+	counter := &limitCounter{limits: retrieval.Limits{MaxBytes: 1024}}
+	gotErr := resolveLFS(context.TODO(), srv.Client(), nil, map[string]string{"": srv.URL + "/owner/repo"}, dir, counter)
+
+	var limitErr *retrieval.LimitError
+	if !errors.As(gotErr, &limitErr) || limitErr.Limit != "max_bytes" {
+		t.Fatalf("resolveLFS() \n\tgotErr: '%+v'\n\twantErr: 'max_bytes'", gotErr)
+	}
+
+	if downloads != 0 {
+		t.Errorf("resolveLFS() downloaded %d object(s) past the limit", downloads)
+	}
+}
+
+func Test_lfsEndpoint(t *testing.T) {
+	var tests = map[string]struct {
+		input string
+		want  string
+	}{
+		"with_git_suffix": {
+			input: "https://github.com/owner/repo.git",
+			want:  "https://github.com/owner/repo.git/info/lfs",
+		},
+
+		"without_git_suffix": {
+			input: "https://github.com/owner/repo",
+			want:  "https://github.com/owner/repo.git/info/lfs",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := lfsEndpoint(tt.input)
+
+			if got != tt.want {
+				t.Errorf("lfsEndpoint(%+v): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_submoduleURL(t *testing.T) {
+	type input struct {
+		parent string
+		url    string
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  string
+	}{
+		"absolute": {
+			input: input{parent: "https://github.com/owner/repo.git", url: "https://gitlab.com/other/sub.git"},
+			want:  "https://gitlab.com/other/sub.git",
+		},
+
+		"relative": {
+			input: input{parent: "https://github.com/owner/repo.git", url: "../sub.git"},
+			want:  "https://github.com/owner/sub.git",
+		},
+
+		"relative_to_owner": {
+			input: input{parent: "https://github.com/owner/repo", url: "../../other/sub.git"},
+			want:  "https://github.com/other/sub.git",
+		},
+
+		"relative_scp_like": {
+			input: input{parent: "git@github.com:owner/repo.git", url: "../sub.git"},
+			want:  "git@github.com:owner/sub.git",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := submoduleURL(tt.input.parent, tt.input.url)
+
+			if got != tt.want {
+				t.Errorf("submoduleURL(%+v): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_lfsRepository(t *testing.T) {
+	remotes := map[string]string{
+		"":                                 "https://github.com/owner/repo",
+		"sub":                              "https://github.com/owner/sub",
+		filepath.Join("sub", "nested"):     "https://github.com/owner/nested",
+		filepath.Join("vendor", "subtree"): "https://github.com/owner/subtree",
+	}
+
+	var tests = map[string]struct {
+		input string
+		want  string
+	}{
+		"top_level":      {input: "a.bin", want: ""},
+		"submodule":      {input: filepath.Join("sub", "a.bin"), want: "sub"},
+		"nested":         {input: filepath.Join("sub", "nested", "a.bin"), want: filepath.Join("sub", "nested")},
+		"sibling_prefix": {input: filepath.Join("subdir", "a.bin"), want: ""},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := lfsRepository(remotes, tt.input)
+
+			if got != tt.want {
+				t.Errorf("lfsRepository(%+v): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_lfsRemotes(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	sub := newLocalRepository(t, map[string]string{"sub.txt": "sub"})
+	defer os.RemoveAll(sub)
+
+	src := newLocalRepository(t, map[string]string{"a.txt": "a"})
+	defer os.RemoveAll(src)
+
+	for _, args := range [][]string{
+		{"-c", "protocol.file.allow=always", "submodule", "add", "-q", sub, "lib"},
+		{"-c", "user.name=neighbor", "-c", "user.email=neighbor@example.com", "commit", "-q", "-m", "add submodule"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", src}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("failed to run git %v: %+v: %s", args, err, out)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "neighbor-git-dst")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo, err := git.PlainClone(dir, false, &git.CloneOptions{
+		URL:               src,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	})
+	if err != nil {
+		t.Fatalf("failed to clone: %+v", err)
+	}
+
+	got, err := lfsRemotes(repo, src)
+	if err != nil {
+		t.Fatalf("lfsRemotes() unexpected error: %+v", err)
+	}
+
+	want := map[string]string{
+		"":    src,
+		"lib": sub,
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("lfsRemotes() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,120 @@
+package git
+
+import (
+	"os"
+	"sync"
+
+	"gopkg.in/src-d/go-billy.v4"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// limitCounter tracks the files and bytes written during a single retrieval and
+// records the first limit that was exceeded.
+type limitCounter struct {
+	mu     sync.Mutex
+	limits retrieval.Limits
+	files  int64
+	bytes  int64
+	err    *retrieval.LimitError
+}
+
+func (c *limitCounter) addFile() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.files++
+	if c.limits.MaxFiles > 0 && c.files > c.limits.MaxFiles && c.err == nil {
+		c.err = &retrieval.LimitError{Limit: "max_files", Max: c.limits.MaxFiles, Actual: c.files}
+	}
+
+	return c.exceeded()
+}
+
+func (c *limitCounter) addBytes(n int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.bytes += n
+	if c.limits.MaxBytes > 0 && c.bytes > c.limits.MaxBytes && c.err == nil {
+		c.err = &retrieval.LimitError{Limit: "max_bytes", Max: c.limits.MaxBytes, Actual: c.bytes}
+	}
+
+	return c.exceeded()
+}
+
+// exceeded returns the first exceeded limit. It must be called with mu held.
+func (c *limitCounter) exceeded() error {
+	if c.err == nil {
+		return nil
+	}
+
+	return c.err
+}
+
+// Err returns the first exceeded limit, if any.
+func (c *limitCounter) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.exceeded()
+}
+
+// limitFilesystem is a billy.Filesystem that fails writes once a limit is exceeded.
+type limitFilesystem struct {
+	billy.Filesystem
+	counter *limitCounter
+	// countFiles is whether created files count towards the file limit. Files
+	// written to the .git directory only count towards the byte limit.
+	countFiles bool
+}
+
+func (fs *limitFilesystem) Create(filename string) (billy.File, error) {
+	return fs.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (fs *limitFilesystem) OpenFile(filename string, flag int, perm os.FileMode) (billy.File, error) {
+	if flag&os.O_CREATE != 0 && fs.countFiles {
+		if err := fs.counter.addFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := fs.Filesystem.OpenFile(filename, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limitFile{File: f, counter: fs.counter}, nil
+}
+
+func (fs *limitFilesystem) TempFile(dir, prefix string) (billy.File, error) {
+	f, err := fs.Filesystem.TempFile(dir, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limitFile{File: f, counter: fs.counter}, nil
+}
+
+func (fs *limitFilesystem) Chroot(path string) (billy.Filesystem, error) {
+	chroot, err := fs.Filesystem.Chroot(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &limitFilesystem{Filesystem: chroot, counter: fs.counter, countFiles: fs.countFiles}, nil
+}
+
+type limitFile struct {
+	billy.File
+	counter *limitCounter
+}
+
+func (f *limitFile) Write(p []byte) (int, error) {
+	if err := f.counter.addBytes(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	return f.File.Write(p)
+}
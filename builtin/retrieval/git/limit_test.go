@@ -0,0 +1,147 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// newLocalRepository creates a Git repository with a commit containing files.
+func newLocalRepository(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "neighbor-git-src")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to init repository: %+v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %+v", err)
+	}
+
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write file: %+v", err)
+		}
+
+		if _, err := wt.Add(name); err != nil {
+			t.Fatalf("failed to add file: %+v", err)
+		}
+	}
+
+	_, err = wt.Commit("initial", &git.CommitOptions{
+		Author: &object.Signature{Name: "neighbor", When: time.Unix(0, 0)},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %+v", err)
+	}
+
+	return dir
+}
+
+func Test_Retrieve_limits(t *testing.T) {
+	src := newLocalRepository(t, map[string]string{
+		"a.txt": "a",
+		"b.txt": "b",
+		"c.txt": strings.Repeat("c", 4096),
+	})
+	defer os.RemoveAll(src)
+
+	type input struct {
+		limits retrieval.Limits
+	}
+
+	type want struct {
+		err *retrieval.LimitError
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"no_limits": {
+			input: input{},
+			want:  want{err: nil},
+		},
+
+		"within_limits": {
+			input: input{
+				limits: retrieval.Limits{MaxFiles: 3, MaxBytes: 1 << 20},
+			},
+			want: want{err: nil},
+		},
+
+		"max_files": {
+			input: input{
+				limits: retrieval.Limits{MaxFiles: 2},
+			},
+			want: want{err: &retrieval.LimitError{Limit: "max_files", Max: 2, Actual: 3}},
+		},
+
+		"max_bytes": {
+			input: input{
+				limits: retrieval.Limits{MaxBytes: 16},
+			},
+			want: want{err: &retrieval.LimitError{Limit: "max_bytes", Max: 16}},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "neighbor-git-dst")
+			if err != nil {
+				t.Fatalf("failed to create directory: %+v", err)
+			}
+			defer os.RemoveAll(tmp)
+
+			dir := filepath.Join(tmp, "project")
+			b := &Backend{limits: tt.input.limits}
+			gotErr := b.Retrieve(context.TODO(), src, dir)
+
+			if tt.want.err == nil {
+				if gotErr != nil {
+					t.Fatalf("Retrieve() unexpected error: %+v", gotErr)
+				}
+
+				if _, err := os.Stat(filepath.Join(dir, "c.txt")); err != nil {
+					t.Errorf("Retrieve() missing file: %+v", err)
+				}
+				return
+			}
+
+			var limitErr *retrieval.LimitError
+			if !errors.As(gotErr, &limitErr) {
+				t.Fatalf("Retrieve() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if limitErr.Limit != tt.want.err.Limit || limitErr.Max != tt.want.err.Max {
+				t.Errorf("Retrieve() mismatched limit: \n\tgot: '%+v'\n\twant: '%+v'", limitErr, tt.want.err)
+			}
+
+			if tt.want.err.Actual != 0 && limitErr.Actual != tt.want.err.Actual {
+				t.Errorf("Retrieve() mismatched actual: \n\tgot: '%+v'\n\twant: '%+v'", limitErr.Actual, tt.want.err.Actual)
+			}
+
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("Retrieve() did not clean up '%s'", dir)
+			}
+		})
+	}
+}
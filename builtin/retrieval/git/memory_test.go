@@ -0,0 +1,106 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+func Test_RetrieveFilesystem(t *testing.T) {
+	src := newLocalRepository(t, map[string]string{
+		"Dockerfile": "FROM scratch\n",
+		"main.go":    "package main\n",
+	})
+	defer os.RemoveAll(src)
+
+	type input struct {
+		backend *Backend
+	}
+
+	type want struct {
+		files    map[string]string
+		err      error
+		limitErr bool
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"retrieved": {
+			input: input{
+				backend: &Backend{},
+			},
+			want: want{
+				files: map[string]string{
+					"Dockerfile": "FROM scratch\n",
+					"main.go":    "package main\n",
+				},
+			},
+		},
+
+		"lfs_unsupported": {
+			input: input{
+				backend: &Backend{lfs: true},
+			},
+			want: want{
+				err: fmt.Errorf("LFS is not supported for in-memory retrieval"),
+			},
+		},
+
+		"max_files": {
+			input: input{
+				backend: &Backend{limits: retrieval.Limits{MaxFiles: 1}},
+			},
+			want: want{
+				limitErr: true,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			fs, gotErr := tt.input.backend.RetrieveFilesystem(context.TODO(), src)
+
+			if tt.want.limitErr {
+				var limitErr *retrieval.LimitError
+				if !errors.As(gotErr, &limitErr) {
+					t.Errorf("RetrieveFilesystem() \n\tgotErr: '%+v'\n\twantErr: *retrieval.LimitError", gotErr)
+				}
+				return
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("RetrieveFilesystem() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			for name, contents := range tt.want.files {
+				f, err := fs.Open(name)
+				if err != nil {
+					t.Errorf("RetrieveFilesystem() missing file '%s': %+v", name, err)
+					continue
+				}
+
+				got, err := ioutil.ReadAll(f)
+				f.Close()
+				if err != nil || string(got) != contents {
+					t.Errorf("RetrieveFilesystem() mismatched contents of '%s': '%s' (%+v)", name, got, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package goproxy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/mod/zip"
+
+	"github.com/mccurdyc/neighbor/builtin/internal/goproxy"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// ErrChecksumMismatch is used to indicate that a downloaded module zip does not
+// match the hash recorded for it in the sum file or the checksum database.
+var ErrChecksumMismatch = fmt.Errorf("checksum mismatch")
+
+// Factory is the factory function for creating the backend for a Go module proxy
+// as a project retrieval method.
+//
+// The optional "proxy" config value is the url of the proxy (defaults to
+// https://proxy.golang.org). file:// urls are supported for local proxies.
+//
+// Every retrieved module is verified against the checksum database of the
+// optional "sumdb" config value, in the format of GOSUMDB (defaults to
+// sum.golang.org), unless it is "off". The optional "sum_file" config value is
+// the path to a go.sum-style file that modules are verified against instead.
+func Factory(ctx context.Context, conf *retrieval.BackendConfig) (retrieval.Backend, error) {
+	c, err := goproxy.NewClient(conf.Config["proxy"], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Backend{
+		client: c,
+	}
+
+	if fp := conf.Config["sum_file"]; len(fp) != 0 {
+		f, err := os.Open(fp)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open sum file: %+v", err)
+		}
+		defer f.Close()
+
+		b.sums, err = parseSums(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sum file: %+v", err)
+		}
+
+		return b, nil
+	}
+
+	db := conf.Config["sumdb"]
+	if db == "off" {
+		return b, nil
+	}
+
+	if len(db) == 0 {
+		db = DefaultSumDB
+	}
+
+	key, u, err := parseSumDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	b.sumDBOps = newSumDBOps(key, u, nil)
+	b.sumDB = sumdb.NewClient(b.sumDBOps)
+
+	return b, nil
+}
+
+// Backend is the backend for project retrieval from a Go module proxy.
+type Backend struct {
+	client *goproxy.Client
+	// sums maps "module version" to the expected h1: hash of the module zip.
+	sums map[string]string
+	// sumDB is the checksum database that is used when there are no sums. Both
+	// being nil disables verification.
+	sumDB    *sumdb.Client
+	sumDBOps *sumDBOps
+}
+
+// Retrieve downloads the module zip specified by src (i.e., `module@version`),
+// verifies it and extracts it to a local dir.
+func (b *Backend) Retrieve(ctx context.Context, src string, dir string) error {
+	path, version, err := goproxy.SplitModuleVersion(src)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile("", "neighbor-goproxy-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %+v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	err = b.client.Zip(ctx, path, version, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download module zip: %w", err)
+	}
+
+	if err := b.verify(path, version, tmp.Name()); err != nil {
+		return err
+	}
+
+	return zip.Unzip(dir, module.Version{Path: path, Version: version}, tmp.Name())
+}
+
+func (b *Backend) verify(path string, version string, zipfile string) error {
+	var want, source string
+
+	switch {
+	case b.sums != nil:
+		var ok bool
+		want, ok = b.sums[path+" "+version]
+		if !ok {
+			return fmt.Errorf("missing sum entry for %s@%s", path, version)
+		}
+		source = "sum file"
+	case b.sumDB != nil:
+		var err error
+		want, err = b.lookup(path, version)
+		if err != nil {
+			return err
+		}
+		source = "sumdb"
+	default:
+		return nil
+	}
+
+	got, err := dirhash.HashZip(zipfile, dirhash.Hash1)
+	if err != nil {
+		return fmt.Errorf("failed to hash module zip: %+v", err)
+	}
+
+	if got != want {
+		return fmt.Errorf("%s@%s: %w\n\tdownloaded: %s\n\t%s: %s", path, version, ErrChecksumMismatch, got, source, want)
+	}
+
+	return nil
+}
+
+// lookup returns the h1: hash of the module zip of path at version recorded in
+// the checksum database.
+func (b *Backend) lookup(path string, version string) (string, error) {
+	lines, err := b.sumDB.Lookup(path, version)
+	if err != nil {
+		if msg := b.sumDBOps.securityErrors(); len(msg) != 0 {
+			return "", fmt.Errorf("failed to look up %s@%s in sumdb: %+v\n%s", path, version, err, msg)
+		}
+		return "", fmt.Errorf("failed to look up %s@%s in sumdb: %+v", path, version, err)
+	}
+
+	for _, l := range lines {
+		if fields := strings.Fields(l); len(fields) == 3 {
+			return fields[2], nil
+		}
+	}
+
+	return "", fmt.Errorf("missing sumdb entry for %s@%s", path, version)
+}
+
+// parseSums parses a go.sum-style file. Only module zip hashes are kept, i.e.,
+// `/go.mod` hashes are ignored.
+func parseSums(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed line %d", n)
+		}
+
+		if strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+
+		sums[fields[0]+" "+fields[1]] = fields[2]
+	}
+
+	return sums, s.Err()
+}
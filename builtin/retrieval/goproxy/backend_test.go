@@ -0,0 +1,296 @@
+package goproxy
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/mod/sumdb"
+	"golang.org/x/mod/sumdb/dirhash"
+	"golang.org/x/mod/sumdb/note"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// newFileProxy creates a file-based module proxy containing a single module
+// version and returns the proxy url and the h1: hash of the module zip.
+func newFileProxy(t *testing.T, path string, version string, files map[string]string) (string, string) {
+	t.Helper()
+
+	root, err := ioutil.TempDir("", "neighbor-goproxy")
+	if err != nil {
+		t.Fatalf("failed to create proxy directory: %+v", err)
+	}
+
+	vdir := filepath.Join(root, filepath.FromSlash(path), "@v")
+	if err := os.MkdirAll(vdir, 0755); err != nil {
+		t.Fatalf("failed to create proxy directory: %+v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(vdir, "list"), []byte(version+"\n"), 0644); err != nil {
+		t.Fatalf("failed to write version list: %+v", err)
+	}
+
+	zf, err := os.Create(filepath.Join(vdir, version+".zip"))
+	if err != nil {
+		t.Fatalf("failed to create module zip: %+v", err)
+	}
+
+	zw := zip.NewWriter(zf)
+	for name, contents := range files {
+		w, err := zw.Create(fmt.Sprintf("%s@%s/%s", path, version, name))
+		if err != nil {
+			t.Fatalf("failed to add file to module zip: %+v", err)
+		}
+
+		if _, err := w.Write([]byte(contents)); err != nil {
+			t.Fatalf("failed to write file to module zip: %+v", err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close module zip: %+v", err)
+	}
+	zf.Close()
+
+	h, err := dirhash.HashZip(zf.Name(), dirhash.Hash1)
+	if err != nil {
+		t.Fatalf("failed to hash module zip: %+v", err)
+	}
+
+	return "file://" + root, h
+}
+
+// newSumDB starts a checksum database that records the h1: hash of each
+// "module version" of sums and returns its sumdb config value.
+func newSumDB(t *testing.T, sums map[string]string) (string, *httptest.Server) {
+	t.Helper()
+
+	skey, vkey, err := note.GenerateKey(rand.Reader, "sum.example.com")
+	if err != nil {
+		t.Fatalf("failed to generate sumdb key: %+v", err)
+	}
+
+	gosum := func(path string, version string) ([]byte, error) {
+		h, ok := sums[path+" "+version]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return []byte(fmt.Sprintf("%s %s %s\n%s %s/go.mod h1:abc=\n", path, version, h, path, version)), nil
+	}
+
+	srv := httptest.NewServer(sumdb.NewServer(sumdb.NewTestServer(skey, gosum)))
+
+	return vkey + " " + srv.URL, srv
+}
+
+func writeSumFile(t *testing.T, contents string) string {
+	t.Helper()
+
+	f, err := ioutil.TempFile("", "neighbor-go.sum")
+	if err != nil {
+		t.Fatalf("failed to create sum file: %+v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("failed to write sum file: %+v", err)
+	}
+
+	return f.Name()
+}
+
+func Test_Retrieve(t *testing.T) {
+	files := map[string]string{
+		"go.mod":  "module example.com/mod\n",
+		"main.go": "package main\n",
+	}
+
+	proxy, hash := newFileProxy(t, "example.com/mod", "v1.0.0", files)
+	defer os.RemoveAll(strings.TrimPrefix(proxy, "file://"))
+
+	goodDB, goodSrv := newSumDB(t, map[string]string{"example.com/mod v1.0.0": hash})
+	defer goodSrv.Close()
+
+	badDB, badSrv := newSumDB(t, map[string]string{"example.com/mod v1.0.0": "h1:abc="})
+	defer badSrv.Close()
+
+	emptyDB, emptySrv := newSumDB(t, nil)
+	defer emptySrv.Close()
+
+	type input struct {
+		src     string
+		sumDB   string
+		sumFile string
+	}
+
+	type want struct {
+		files   []string
+		err     error
+		errType error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"missing_version": {
+			input: input{
+				src: "example.com/mod",
+			},
+			want: want{
+				err: fmt.Errorf("'example.com/mod' must be of the form module@version"),
+			},
+		},
+
+		"no_verification": {
+			input: input{
+				src:   "example.com/mod@v1.0.0",
+				sumDB: "off",
+			},
+			want: want{
+				files: []string{"go.mod", "main.go"},
+			},
+		},
+
+		"verified_sumdb": {
+			input: input{
+				src:   "example.com/mod@v1.0.0",
+				sumDB: goodDB,
+			},
+			want: want{
+				files: []string{"go.mod", "main.go"},
+			},
+		},
+
+		"missing_sumdb_entry": {
+			input: input{
+				src:   "example.com/mod@v1.0.0",
+				sumDB: emptyDB,
+			},
+			want: want{
+				err: fmt.Errorf("failed to look up example.com/mod@v1.0.0 in sumdb: example.com/mod@v1.0.0: unexpected response from sumdb for '/lookup/example.com/mod@v1.0.0': 404 Not Found"),
+			},
+		},
+
+		"sumdb_checksum_mismatch": {
+			input: input{
+				src:   "example.com/mod@v1.0.0",
+				sumDB: badDB,
+			},
+			want: want{
+				errType: ErrChecksumMismatch,
+			},
+		},
+
+		"sum_file_instead_of_sumdb": {
+			input: input{
+				src:     "example.com/mod@v1.0.0",
+				sumDB:   badDB,
+				sumFile: fmt.Sprintf("example.com/mod v1.0.0 %s\n", hash),
+			},
+			want: want{
+				files: []string{"go.mod", "main.go"},
+			},
+		},
+
+		"verified": {
+			input: input{
+				src:     "example.com/mod@v1.0.0",
+				sumFile: fmt.Sprintf("example.com/mod v1.0.0 %s\nexample.com/mod v1.0.0/go.mod h1:abc=\n", hash),
+			},
+			want: want{
+				files: []string{"go.mod", "main.go"},
+			},
+		},
+
+		"missing_sum_entry": {
+			input: input{
+				src:     "example.com/mod@v1.0.0",
+				sumFile: "example.com/other v1.0.0 h1:abc=\n",
+			},
+			want: want{
+				err: fmt.Errorf("missing sum entry for example.com/mod@v1.0.0"),
+			},
+		},
+
+		"checksum_mismatch": {
+			input: input{
+				src:     "example.com/mod@v1.0.0",
+				sumFile: "example.com/mod v1.0.0 h1:abc=\n",
+			},
+			want: want{
+				errType: ErrChecksumMismatch,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			conf := &retrieval.BackendConfig{
+				Config: map[string]string{"proxy": proxy, "sumdb": tt.input.sumDB},
+			}
+
+			if len(tt.input.sumFile) != 0 {
+				fp := writeSumFile(t, tt.input.sumFile)
+				defer os.Remove(fp)
+				conf.Config["sum_file"] = fp
+			}
+
+			b, err := Factory(context.TODO(), conf)
+			if err != nil {
+				t.Fatalf("Factory() unexpected error: %+v", err)
+			}
+
+			dir, err := ioutil.TempDir("", "neighbor-goproxy-dst")
+			if err != nil {
+				t.Fatalf("failed to create destination directory: %+v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			dst := filepath.Join(dir, "mod")
+			gotErr := b.Retrieve(context.TODO(), tt.input.src, dst)
+
+			if tt.want.errType != nil {
+				if !errors.Is(gotErr, tt.want.errType) {
+					t.Errorf("Retrieve() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.errType)
+				}
+				return
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Retrieve() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			for _, f := range tt.want.files {
+				b, err := ioutil.ReadFile(filepath.Join(dst, f))
+				if err != nil {
+					t.Errorf("Retrieve() missing file '%s': %+v", f, err)
+					continue
+				}
+
+				if string(b) != files[f] {
+					t.Errorf("Retrieve() mismatched contents of '%s': \n\tgot: '%s'\n\twant: '%s'", f, b, files[f])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package goproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/mod/sumdb"
+)
+
+// DefaultSumDB is the checksum database that modules are verified against when
+// one is not specified. Its public key is the one built into the go command.
+const DefaultSumDB = "sum.golang.org+033de0ae+Ac4zctda0e5eza+HJyk9SxEdh+s3Ld4pbCbQ3ZxPgRN"
+
+// knownSumDBs are the keys of the checksum databases that can be specified by
+// name alone.
+var knownSumDBs = map[string]string{
+	"sum.golang.org": DefaultSumDB,
+}
+
+// parseSumDB parses a checksum database in the format of GOSUMDB, i.e., a known
+// name or `name+key [url]`, and returns its verifier key and url. The url
+// defaults to https://<name>.
+func parseSumDB(s string) (string, string, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", "", fmt.Errorf("invalid sumdb '%s'", s)
+	}
+
+	key := fields[0]
+	if known, ok := knownSumDBs[key]; ok {
+		key = known
+	}
+
+	i := strings.Index(key, "+")
+	if i <= 0 {
+		return "", "", fmt.Errorf("unknown sumdb '%s' (the key is required, i.e., name+key [url])", s)
+	}
+	name := key[:i]
+
+	u := "https://" + name
+	if len(fields) == 2 {
+		u = fields[1]
+	}
+
+	return key, strings.TrimSuffix(u, "/"), nil
+}
+
+// sumDBOps are the operations of a checksum database client that reads from the
+// database at url over HTTP and keeps its config and cache in memory.
+type sumDBOps struct {
+	key        string
+	url        string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	config   map[string][]byte
+	cache    map[string][]byte
+	security []string
+}
+
+func newSumDBOps(key string, url string, c *http.Client) *sumDBOps {
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	return &sumDBOps{
+		key:        key,
+		url:        url,
+		httpClient: c,
+		config:     make(map[string][]byte),
+		cache:      make(map[string][]byte),
+	}
+}
+
+func (o *sumDBOps) ReadRemote(path string) ([]byte, error) {
+	resp, err := o.httpClient.Get(o.url + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response from sumdb for '%s': %s", path, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (o *sumDBOps) ReadConfig(file string) ([]byte, error) {
+	if file == "key" {
+		return []byte(o.key), nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// a missing latest tree is empty, which makes the client start from scratch.
+	return o.config[file], nil
+}
+
+func (o *sumDBOps) WriteConfig(file string, old []byte, new []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if !bytes.Equal(o.config[file], old) {
+		return sumdb.ErrWriteConflict
+	}
+	o.config[file] = new
+
+	return nil
+}
+
+func (o *sumDBOps) ReadCache(file string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	data, ok := o.cache[file]
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not cached", file)
+	}
+
+	return data, nil
+}
+
+func (o *sumDBOps) WriteCache(file string, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.cache[file] = data
+}
+
+func (o *sumDBOps) Log(msg string) {}
+
+// SecurityError records msg, which is reported with the error that the client
+// returns.
+func (o *sumDBOps) SecurityError(msg string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.security = append(o.security, msg)
+}
+
+// securityErrors returns the security errors reported by the client.
+func (o *sumDBOps) securityErrors() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return strings.Join(o.security, "\n")
+}
@@ -0,0 +1,79 @@
+package goproxy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_parseSumDB(t *testing.T) {
+	type want struct {
+		key string
+		url string
+		err error
+	}
+
+	var tests = map[string]struct {
+		input string
+		want  want
+	}{
+		"known_name": {
+			input: "sum.golang.org",
+			want: want{
+				key: DefaultSumDB,
+				url: "https://sum.golang.org",
+			},
+		},
+
+		"key": {
+			input: "sum.example.com+01234567+AAAA",
+			want: want{
+				key: "sum.example.com+01234567+AAAA",
+				url: "https://sum.example.com",
+			},
+		},
+
+		"key_and_url": {
+			input: "sum.example.com+01234567+AAAA https://proxy.example.com/sumdb/sum.example.com/",
+			want: want{
+				key: "sum.example.com+01234567+AAAA",
+				url: "https://proxy.example.com/sumdb/sum.example.com",
+			},
+		},
+
+		"unknown_name": {
+			input: "sum.example.com",
+			want: want{
+				err: fmt.Errorf("unknown sumdb 'sum.example.com' (the key is required, i.e., name+key [url])"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			key, u, gotErr := parseSumDB(tt.input)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("parseSumDB() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if diff := cmp.Diff(tt.want.key, key); diff != "" {
+				t.Errorf("parseSumDB() key mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.want.url, u); diff != "" {
+				t.Errorf("parseSumDB() url mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
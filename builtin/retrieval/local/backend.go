@@ -0,0 +1,238 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// Mode is the method used to place a local project in the projects directory.
+type Mode = string
+
+const (
+	// Auto tries reflinking files, then cloning the source if it is a Git
+	// repository without uncommitted changes, then copying files. Hardlinking is
+	// never tried because it is not safe to modify the projects of the source.
+	Auto Mode = "auto"
+	// Reflink requires files to be reflinked (i.e., copy-on-write clones) and
+	// fails otherwise.
+	Reflink Mode = "reflink"
+	// Hardlink hardlinks files. This is nearly free, but files that are modified
+	// in place (as opposed to being replaced) are also modified in the source.
+	Hardlink Mode = "hardlink"
+	// Copy copies the contents of every file.
+	Copy Mode = "copy"
+	// Clone clones the source Git repository, which hardlinks its (immutable)
+	// objects when possible and checks out its HEAD. Unlike a worktree, a clone
+	// does not write to the source, but uncommitted changes are not retrieved.
+	Clone Mode = "clone"
+)
+
+// autoModes are the modes that Auto tries, in order.
+var autoModes = []Mode{Reflink, Clone, Copy}
+
+// Factory is the factory function for creating the backend for local directories
+// and local Git repositories as a project retrieval method.
+//
+// The optional "mode" config value is one of "auto" (default), "reflink",
+// "hardlink", "copy" or "clone".
+func Factory(ctx context.Context, conf *retrieval.BackendConfig) (retrieval.Backend, error) {
+	mode := conf.Config["mode"]
+	if len(mode) == 0 {
+		mode = Auto
+	}
+
+	switch strings.ToLower(mode) {
+	case Auto, Reflink, Hardlink, Copy:
+	case Clone:
+		if _, err := exec.LookPath("git"); err != nil {
+			return nil, fmt.Errorf("failed to find git for clone mode: '%+v'", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported mode '%s'", mode)
+	}
+
+	return &Backend{
+		mode: strings.ToLower(mode),
+	}, nil
+}
+
+// Backend is the backend for project retrieval from a local directory.
+type Backend struct {
+	mode Mode
+}
+
+// Retrieve places a copy of the local directory or Git repository src at dir.
+// Nothing is ever written to src.
+func (b *Backend) Retrieve(ctx context.Context, src string, dir string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("source '%s' must be a directory", src)
+	}
+
+	if b.mode != Auto {
+		return place(ctx, b.mode, src, dir)
+	}
+
+	for _, mode := range autoModes {
+		if mode == Clone && !cleanRepository(ctx, src) {
+			continue
+		}
+
+		err = place(ctx, mode, src, dir)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		// the next mode starts from scratch.
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// place places src at dir with mode, which is not Auto.
+func place(ctx context.Context, mode Mode, src string, dir string) error {
+	if mode == Clone {
+		return clone(ctx, src, dir)
+	}
+
+	// directories are created writable, so that the contents of read-only source
+	// directories can be placed in them, and get the mode of their source after.
+	type dirMode struct {
+		path string
+		perm os.FileMode
+	}
+	var dirs []dirMode
+
+	err := filepath.Walk(src, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+
+		switch {
+		case info.IsDir():
+			dirs = append(dirs, dirMode{path: target, perm: info.Mode().Perm()})
+			return os.MkdirAll(target, 0755)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return placeFile(mode, p, target, info.Mode().Perm())
+		}
+
+		// sockets, devices, etc. are not part of a project.
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// directories are walked before their contents, so the deepest are last.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func placeFile(mode Mode, src string, dst string, perm os.FileMode) error {
+	switch mode {
+	case Hardlink:
+		return os.Link(src, dst)
+	case Reflink:
+		return reflink(src, dst, perm)
+	}
+
+	return copyFile(src, dst, perm)
+}
+
+func copyFile(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// cleanRepository returns whether src is the top-level directory of a Git
+// repository without uncommitted changes or untracked files, i.e., whether a
+// clone of it has the same files.
+func cleanRepository(ctx context.Context, src string) bool {
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return false
+	}
+
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return false
+	}
+
+	// optional locks, e.g., of refreshing the index, would write to src.
+	out, err := exec.CommandContext(ctx, "git", "--no-optional-locks", "-C", abs, "rev-parse", "--show-toplevel").Output()
+	if err != nil || filepath.Clean(strings.TrimSpace(string(out))) != filepath.Clean(abs) {
+		return false
+	}
+
+	out, err = exec.CommandContext(ctx, "git", "--no-optional-locks", "-C", abs, "status", "--porcelain", "--ignored").Output()
+	return err == nil && len(bytes.TrimSpace(out)) == 0
+}
+
+// clone clones the Git repository at src to dir and checks out its HEAD.
+func clone(ctx context.Context, src string, dir string) error {
+	abs, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", "clone", "--quiet", "--", abs, dir)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to clone: %+v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,408 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+func Test_Factory(t *testing.T) {
+	type input struct {
+		conf *retrieval.BackendConfig
+	}
+
+	type want struct {
+		be  *Backend
+		err error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"default_mode": {
+			input: input{
+				conf: &retrieval.BackendConfig{},
+			},
+			want: want{
+				be:  &Backend{mode: Auto},
+				err: nil,
+			},
+		},
+
+		"hardlink_mode": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"mode": "HardLink"},
+				},
+			},
+			want: want{
+				be:  &Backend{mode: Hardlink},
+				err: nil,
+			},
+		},
+
+		"clone_mode": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"mode": "clone"},
+				},
+			},
+			want: want{
+				be:  &Backend{mode: Clone},
+				err: nil,
+			},
+		},
+
+		"unsupported_mode": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"mode": "teleport"},
+				},
+			},
+			want: want{
+				be:  nil,
+				err: fmt.Errorf("unsupported mode 'teleport'"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := Factory(context.TODO(), tt.input.conf)
+
+			if tt.want.be == nil {
+				if got != nil {
+					t.Errorf("Factory() mismatched nil")
+				}
+			} else if diff := cmp.Diff(tt.want.be, got, cmp.AllowUnexported(Backend{})); diff != "" {
+				t.Errorf("Factory() mismatched backend (-want +got):\n%s", diff)
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Factory(): \n\tgotErr: '%v'\n\twantErr: '%v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
+
+func newSourceDir(t *testing.T) string {
+	t.Helper()
+
+	src, err := ioutil.TempDir("", "neighbor-local-src")
+	if err != nil {
+		t.Fatalf("failed to create source directory: %+v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(src, "a", "b"), 0755); err != nil {
+		t.Fatalf("failed to create source directory: %+v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "a", "b", "c.txt"), []byte("c"), 0644); err != nil {
+		t.Fatalf("failed to write source file: %+v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "root.txt"), []byte("root"), 0644); err != nil {
+		t.Fatalf("failed to write source file: %+v", err)
+	}
+
+	if err := os.Symlink("root.txt", filepath.Join(src, "link.txt")); err != nil {
+		t.Fatalf("failed to create symlink: %+v", err)
+	}
+
+	return src
+}
+
+func Test_Retrieve(t *testing.T) {
+	type input struct {
+		mode Mode
+	}
+
+	type want struct {
+		sameFile bool
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"auto": {
+			input: input{mode: Auto},
+			want:  want{sameFile: false},
+		},
+
+		"copy": {
+			input: input{mode: Copy},
+			want:  want{sameFile: false},
+		},
+
+		"hardlink": {
+			input: input{mode: Hardlink},
+			want:  want{sameFile: true},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			src := newSourceDir(t)
+			defer os.RemoveAll(src)
+
+			dst, err := ioutil.TempDir("", "neighbor-local-dst")
+			if err != nil {
+				t.Fatalf("failed to create destination directory: %+v", err)
+			}
+			defer os.RemoveAll(dst)
+
+			dir := filepath.Join(dst, "project")
+			b := &Backend{mode: tt.input.mode}
+			if err := b.Retrieve(context.TODO(), src, dir); err != nil {
+				t.Fatalf("Retrieve() unexpected error: %+v", err)
+			}
+
+			got, err := ioutil.ReadFile(filepath.Join(dir, "a", "b", "c.txt"))
+			if err != nil || string(got) != "c" {
+				t.Errorf("Retrieve() mismatched nested file: '%s' (%+v)", got, err)
+			}
+
+			link, err := os.Readlink(filepath.Join(dir, "link.txt"))
+			if err != nil || link != "root.txt" {
+				t.Errorf("Retrieve() mismatched symlink: '%s' (%+v)", link, err)
+			}
+
+			srcInfo, _ := os.Stat(filepath.Join(src, "root.txt"))
+			dstInfo, _ := os.Stat(filepath.Join(dir, "root.txt"))
+			if os.SameFile(srcInfo, dstInfo) != tt.want.sameFile {
+				t.Errorf("Retrieve() mismatched same file: \n\tgot: '%+v'\n\twant: '%+v'", !tt.want.sameFile, tt.want.sameFile)
+			}
+		})
+	}
+}
+
+// makeWritable makes dir and the directories in it writable, so that they can be
+// removed.
+func makeWritable(dir string) {
+	filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			os.Chmod(p, 0755)
+		}
+		return nil
+	})
+}
+
+func Test_Retrieve_readOnly(t *testing.T) {
+	var tests = map[string]struct {
+		input Mode
+	}{
+		"auto":     {input: Auto},
+		"copy":     {input: Copy},
+		"hardlink": {input: Hardlink},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			src := newSourceDir(t)
+			defer os.RemoveAll(src)
+			defer makeWritable(src)
+
+			// e.g., a mounted corpus or the Go module cache.
+			for _, d := range []string{filepath.Join(src, "a", "b"), filepath.Join(src, "a"), src} {
+				if err := os.Chmod(d, 0555); err != nil {
+					t.Fatalf("failed to make source directory read-only: %+v", err)
+				}
+			}
+
+			dst, err := ioutil.TempDir("", "neighbor-local-dst")
+			if err != nil {
+				t.Fatalf("failed to create destination directory: %+v", err)
+			}
+			defer os.RemoveAll(dst)
+			defer makeWritable(dst)
+
+			dir := filepath.Join(dst, "project")
+			b := &Backend{mode: tt.input}
+			if err := b.Retrieve(context.TODO(), src, dir); err != nil {
+				t.Fatalf("Retrieve() unexpected error: %+v", err)
+			}
+
+			got, err := ioutil.ReadFile(filepath.Join(dir, "a", "b", "c.txt"))
+			if err != nil || string(got) != "c" {
+				t.Errorf("Retrieve() mismatched nested file: '%s' (%+v)", got, err)
+			}
+
+			for _, d := range []string{dir, filepath.Join(dir, "a"), filepath.Join(dir, "a", "b")} {
+				info, err := os.Stat(d)
+				if err != nil {
+					t.Fatalf("Retrieve() missing directory: %+v", err)
+				}
+
+				if info.Mode().Perm() != 0555 {
+					t.Errorf("Retrieve() mismatched mode of '%s': \n\tgot: '%+v'\n\twant: '%+v'", d, info.Mode().Perm(), os.FileMode(0555))
+				}
+			}
+		})
+	}
+}
+
+// newSourceRepository creates a Git repository with the files of newSourceDir
+// committed.
+func newSourceRepository(t *testing.T) string {
+	t.Helper()
+
+	src := newSourceDir(t)
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=neighbor", "-c", "user.email=neighbor@example.com", "commit", "-q", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", src}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("failed to run git %v: %+v: %s", args, err, out)
+		}
+	}
+
+	return src
+}
+
+// snapshot returns the size and modification time of every file under dir.
+func snapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		files[p] = fmt.Sprintf("%d %s", info.Size(), info.ModTime())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to walk '%s': %+v", dir, err)
+	}
+
+	return files
+}
+
+func Test_Retrieve_repository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	type input struct {
+		mode      Mode
+		untracked bool
+	}
+
+	type want struct {
+		files []string
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"clone": {
+			input: input{mode: Clone},
+			want:  want{files: []string{"root.txt", "a/b/c.txt"}},
+		},
+
+		"auto_clean": {
+			input: input{mode: Auto},
+			want:  want{files: []string{"root.txt", "a/b/c.txt"}},
+		},
+
+		"auto_untracked_files": {
+			input: input{mode: Auto, untracked: true},
+			want:  want{files: []string{"root.txt", "a/b/c.txt", "untracked.txt"}},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			src := newSourceRepository(t)
+			defer os.RemoveAll(src)
+
+			if tt.input.untracked {
+				if err := ioutil.WriteFile(filepath.Join(src, "untracked.txt"), []byte("untracked"), 0644); err != nil {
+					t.Fatalf("failed to write source file: %+v", err)
+				}
+			}
+
+			dst, err := ioutil.TempDir("", "neighbor-local-dst")
+			if err != nil {
+				t.Fatalf("failed to create destination directory: %+v", err)
+			}
+			defer os.RemoveAll(dst)
+
+			before := snapshot(t, src)
+
+			dir := filepath.Join(dst, "project")
+			b := &Backend{mode: tt.input.mode}
+			if err := b.Retrieve(context.TODO(), src, dir); err != nil {
+				t.Fatalf("Retrieve() unexpected error: %+v", err)
+			}
+
+			for _, f := range tt.want.files {
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(f))); err != nil {
+					t.Errorf("Retrieve() missing file '%s': %+v", f, err)
+				}
+			}
+
+			if diff := cmp.Diff(before, snapshot(t, src)); diff != "" {
+				t.Errorf("Retrieve() modified the source (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_cleanRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	src := newSourceRepository(t)
+	defer os.RemoveAll(src)
+
+	if !cleanRepository(context.TODO(), src) {
+		t.Errorf("cleanRepository() of a committed repository: \n\tgot: 'false'\n\twant: 'true'")
+	}
+
+	if cleanRepository(context.TODO(), filepath.Join(src, "a")) {
+		t.Errorf("cleanRepository() of a subdirectory: \n\tgot: 'true'\n\twant: 'false'")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "root.txt"), []byte("modified"), 0644); err != nil {
+		t.Fatalf("failed to write source file: %+v", err)
+	}
+
+	if cleanRepository(context.TODO(), src) {
+		t.Errorf("cleanRepository() of a modified repository: \n\tgot: 'true'\n\twant: 'false'")
+	}
+
+	dir := newSourceDir(t)
+	defer os.RemoveAll(dir)
+
+	if cleanRepository(context.TODO(), dir) {
+		t.Errorf("cleanRepository() of a directory: \n\tgot: 'true'\n\twant: 'false'")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build linux
+// +build linux
+
+package local
+
+import (
+	"os"
+	"syscall"
+)
+
+// ficlone is the FICLONE ioctl request number from linux/fs.h.
+const ficlone = 0x40049409
+
+// reflink creates dst as a copy-on-write clone of src. This is supported by
+// filesystems such as Btrfs, XFS and OverlayFS.
+func reflink(src string, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, out.Fd(), ficlone, in.Fd())
+	if cerr := out.Close(); errno == 0 && cerr != nil {
+		return cerr
+	}
+
+	if errno != 0 {
+		os.Remove(dst)
+		return &os.LinkError{Op: "reflink", Old: src, New: dst, Err: errno}
+	}
+
+	return nil
+}
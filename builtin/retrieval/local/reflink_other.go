@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package local
+
+import (
+	"fmt"
+	"os"
+)
+
+// reflink is not supported on this platform.
+func reflink(src string, dst string, _ os.FileMode) error {
+	return &os.LinkError{Op: "reflink", Old: src, New: dst, Err: fmt.Errorf("unsupported platform")}
+}
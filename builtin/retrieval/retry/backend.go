@@ -0,0 +1,211 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	nethttp "net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 1 * time.Second
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// Wrap wraps a retrieval backend so that transient failures are retried with
+// exponential backoff and jitter.
+//
+// The optional "max_attempts" (defaults to 3), "base_delay" (defaults to 1s)
+// and "max_delay" (defaults to 30s) config values control the retries. Delays
+// are parsed with time.ParseDuration.
+func Wrap(ctx context.Context, b retrieval.Backend, conf *retrieval.BackendConfig) (*Backend, error) {
+	if b == nil {
+		return nil, fmt.Errorf("retrieval backend cannot be nil")
+	}
+
+	maxAttempts := defaultMaxAttempts
+	if v := conf.Config["max_attempts"]; len(v) != 0 {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("max_attempts must be a positive integer")
+		}
+		maxAttempts = n
+	}
+
+	baseDelay, err := parseDelay(conf.Config, "base_delay", defaultBaseDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDelay, err := parseDelay(conf.Config, "max_delay", defaultMaxDelay)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		backend:     b,
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		classify:    IsTransient,
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+func parseDelay(conf map[string]string, key string, def time.Duration) (time.Duration, error) {
+	v := conf[key]
+	if len(v) == 0 {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a non-negative duration", key)
+	}
+
+	return d, nil
+}
+
+// Backend is a retrieval backend that retries transient failures of the wrapped
+// retrieval backend.
+type Backend struct {
+	backend     retrieval.Backend
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	classify    func(error) bool
+	rand        *rand.Rand
+}
+
+// Error is returned when a retrieval failed permanently or was still failing
+// after the maximum number of attempts.
+type Error struct {
+	Attempts int
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed after %d attempt(s): %+v", e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retrieve retrieves the project at src to dir, retrying transient failures.
+func (b *Backend) Retrieve(ctx context.Context, src string, dir string) error {
+	_, err := b.RetrieveWithAttempts(ctx, src, dir)
+	return err
+}
+
+// RetrieveWithAttempts retrieves the project at src to dir, retrying transient
+// failures, and returns the number of attempts it took. If retrieval fails, the
+// returned error is an *Error.
+func (b *Backend) RetrieveWithAttempts(ctx context.Context, src string, dir string) (int, error) {
+	// only clean up what the wrapped backend wrote, never a pre-existing directory.
+	_, statErr := os.Stat(dir)
+	cleanUp := os.IsNotExist(statErr)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = b.backend.Retrieve(ctx, src, dir)
+		if err == nil {
+			return attempt, nil
+		}
+
+		if attempt >= b.maxAttempts || !b.classify(err) || ctx.Err() != nil {
+			return attempt, &Error{Attempts: attempt, Err: err}
+		}
+
+		if cleanUp {
+			if rmErr := os.RemoveAll(dir); rmErr != nil {
+				return attempt, &Error{Attempts: attempt, Err: fmt.Errorf("failed to clean up '%s' after %+v: %+v", dir, err, rmErr)}
+			}
+		}
+
+		t := time.NewTimer(b.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return attempt, &Error{Attempts: attempt, Err: ctx.Err()}
+		case <-t.C:
+		}
+	}
+}
+
+// backoff returns a random delay between zero and the exponential backoff for
+// the attempt, capped at the max delay (i.e., "full jitter").
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func (b *Backend) backoff(attempt int) time.Duration {
+	d := b.maxDelay
+	if attempt < 32 {
+		if exp := b.baseDelay << uint(attempt-1); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return time.Duration(b.rand.Int63n(int64(d) + 1))
+}
+
+// IsTransient classifies an error returned by a retriever as transient (i.e.,
+// the retrieval may succeed if retried) or permanent.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var transient *retrieval.TransientError
+	if errors.As(err, &transient) {
+		return true
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	// other network errors, e.g., hosts that do not exist, unsupported url schemes
+	// or invalid certificates, fail the same way when retried.
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout()
+	}
+
+	// go-git does not support unwrapping its errors.
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		var httpErr *http.Err
+		if errors.As(unexpected.Err, &httpErr) {
+			code := httpErr.StatusCode()
+			return code >= nethttp.StatusInternalServerError || code == nethttp.StatusTooManyRequests
+		}
+
+		return IsTransient(unexpected.Err)
+	}
+
+	return false
+}
@@ -0,0 +1,287 @@
+package retry
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+type mockBackend struct {
+	errs  []error
+	calls int
+	// dirExisted records whether dir existed at the start of each call.
+	dirExisted []bool
+}
+
+// Retrieve writes a partial file to dir and returns the next error.
+func (m *mockBackend) Retrieve(_ context.Context, _ string, dir string) error {
+	_, err := os.Stat(dir)
+	m.dirExisted = append(m.dirExisted, err == nil)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "partial"), []byte("partial"), 0644); err != nil {
+		return err
+	}
+
+	m.calls++
+	if m.calls > len(m.errs) {
+		return nil
+	}
+
+	return m.errs[m.calls-1]
+}
+
+func Test_Wrap(t *testing.T) {
+	type input struct {
+		conf *retrieval.BackendConfig
+	}
+
+	type want struct {
+		err error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"defaults": {
+			input: input{
+				conf: &retrieval.BackendConfig{},
+			},
+			want: want{
+				err: nil,
+			},
+		},
+
+		"invalid_max_attempts": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"max_attempts": "0"},
+				},
+			},
+			want: want{
+				err: fmt.Errorf("max_attempts must be a positive integer"),
+			},
+		},
+
+		"invalid_base_delay": {
+			input: input{
+				conf: &retrieval.BackendConfig{
+					Config: map[string]string{"base_delay": "soon"},
+				},
+			},
+			want: want{
+				err: fmt.Errorf("base_delay must be a non-negative duration"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, gotErr := Wrap(context.TODO(), &mockBackend{}, tt.input.conf)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Wrap() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
+
+func Test_RetrieveWithAttempts(t *testing.T) {
+	transient := &retrieval.TransientError{Err: fmt.Errorf("502 bad gateway")}
+
+	type input struct {
+		errs     []error
+		canceled bool
+	}
+
+	type want struct {
+		attempts   int
+		dirExisted []bool
+		err        error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"first_attempt": {
+			input: input{},
+			want: want{
+				attempts:   1,
+				dirExisted: []bool{false},
+				err:        nil,
+			},
+		},
+
+		"transient_then_success": {
+			input: input{
+				errs: []error{transient, transient},
+			},
+			want: want{
+				attempts:   3,
+				dirExisted: []bool{false, false, false},
+				err:        nil,
+			},
+		},
+
+		"permanent": {
+			input: input{
+				errs: []error{transport.ErrRepositoryNotFound},
+			},
+			want: want{
+				attempts:   1,
+				dirExisted: []bool{false},
+				err:        &Error{Attempts: 1, Err: transport.ErrRepositoryNotFound},
+			},
+		},
+
+		"exhausted": {
+			input: input{
+				errs: []error{transient, transient, transient, transient},
+			},
+			want: want{
+				attempts:   3,
+				dirExisted: []bool{false, false, false},
+				err:        &Error{Attempts: 3, Err: transient},
+			},
+		},
+
+		"canceled": {
+			input: input{
+				errs:     []error{transient},
+				canceled: true,
+			},
+			want: want{
+				attempts:   1,
+				dirExisted: []bool{false},
+				err:        &Error{Attempts: 1, Err: transient},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "neighbor-retry")
+			if err != nil {
+				t.Fatalf("failed to create directory: %+v", err)
+			}
+			defer os.RemoveAll(tmp)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.input.canceled {
+				cancel()
+			}
+
+			m := &mockBackend{errs: tt.input.errs}
+			b := &Backend{
+				backend:     m,
+				maxAttempts: 3,
+				classify:    IsTransient,
+				rand:        rand.New(rand.NewSource(1)),
+			}
+
+			got, gotErr := b.RetrieveWithAttempts(ctx, "src", filepath.Join(tmp, "project"))
+
+			if got != tt.want.attempts {
+				t.Errorf("RetrieveWithAttempts() mismatched attempts: \n\tgot: '%+v'\n\twant: '%+v'", got, tt.want.attempts)
+			}
+
+			if fmt.Sprint(m.dirExisted) != fmt.Sprint(tt.want.dirExisted) {
+				t.Errorf("RetrieveWithAttempts() mismatched cleanup: \n\tgot: '%+v'\n\twant: '%+v'", m.dirExisted, tt.want.dirExisted)
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("RetrieveWithAttempts() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
+
+func Test_IsTransient(t *testing.T) {
+	httpErr := func(code int) error {
+		return plumbing.NewUnexpectedError(&http.Err{
+			Response: &nethttp.Response{
+				StatusCode: code,
+				Request:    &nethttp.Request{URL: &url.URL{}},
+			},
+		})
+	}
+
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://github.com/owner/repo/info/refs", Err: err}
+	}
+
+	var tests = map[string]struct {
+		input error
+		want  bool
+	}{
+		"nil":                {input: nil, want: false},
+		"canceled":           {input: context.Canceled, want: false},
+		"not_found":          {input: transport.ErrRepositoryNotFound, want: false},
+		"auth_required":      {input: transport.ErrAuthenticationRequired, want: false},
+		"unexpected_eof":     {input: fmt.Errorf("reading pack: %w", io.ErrUnexpectedEOF), want: true},
+		"marked_transient":   {input: &retrieval.TransientError{Err: errors.New("blip")}, want: true},
+		"timeout":            {input: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "i/o timeout", Name: "github.com", IsTimeout: true}}), want: true},
+		"connection_refused": {input: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), want: true},
+		"connection_reset":   {input: urlErr(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), want: true},
+		"no_such_host":       {input: urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "github.invalid", IsNotFound: true}}), want: false},
+		"unsupported_scheme": {input: urlErr(errors.New("unsupported protocol scheme \"ftp\"")), want: false},
+		"unknown_authority":  {input: urlErr(x509.UnknownAuthorityError{}), want: false},
+		"invalid_hostname":   {input: urlErr(x509.HostnameError{Certificate: &x509.Certificate{}, Host: "github.com"}), want: false},
+		"server_error":       {input: httpErr(nethttp.StatusBadGateway), want: true},
+		"too_many_requests":  {input: httpErr(nethttp.StatusTooManyRequests), want: true},
+		"client_error":       {input: httpErr(nethttp.StatusBadRequest), want: false},
+		"unclassified_error": {input: errors.New("something else"), want: false},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := IsTransient(tt.input)
+
+			if got != tt.want {
+				t.Errorf("IsTransient(%+v): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want)
+			}
+		})
+	}
+}
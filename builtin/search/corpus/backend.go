@@ -0,0 +1,84 @@
+package corpus
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/mccurdyc/neighbor/builtin/project/generic"
+	"github.com/mccurdyc/neighbor/sdk/corpus"
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// Factory is the factory function to be used to create a search backend for the
+// projects in a corpus archive (see sdk/corpus).
+//
+// The required "archive" config value is the path to the archive. The optional
+// "manifest" config value is the path to a separately published manifest, which
+// is used instead of the manifest in the archive.
+func Factory(ctx context.Context, conf *search.BackendConfig) (search.Backend, error) {
+	if conf.SearchMethod != search.Project {
+		return nil, fmt.Errorf("only the Project search method is supported")
+	}
+
+	archive := conf.Config["archive"]
+	if len(archive) == 0 {
+		return nil, fmt.Errorf("archive cannot be empty")
+	}
+
+	m, err := corpus.LoadManifest(archive, conf.Config["manifest"])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		manifest: m,
+	}, nil
+}
+
+// Backend is a corpus archive search backend.
+type Backend struct {
+	manifest *corpus.Manifest
+}
+
+// Search returns the projects in the manifest whose name matches query, which is
+// a path.Match pattern (e.g., `owner/*`). An empty query matches every project.
+// The source location of each project is where it was exported from or, if the
+// manifest does not record it, its name.
+func (b *Backend) Search(ctx context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
+	if _, err := path.Match(query, ""); err != nil {
+		return nil, fmt.Errorf("invalid query '%s': %+v", query, err)
+	}
+
+	res := make([]project.Backend, 0, numDesiredResults)
+	for _, e := range b.manifest.Projects {
+		if len(query) != 0 {
+			if ok, _ := path.Match(query, e.Name); !ok {
+				continue
+			}
+		}
+
+		loc := e.Source
+		if len(loc) == 0 {
+			loc = e.Name
+		}
+
+		p, err := generic.Factory(ctx, &project.BackendConfig{
+			Name:           e.Name,
+			Version:        e.Version,
+			SourceLocation: loc,
+			Metadata:       e.Metadata,
+		})
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, p)
+		if len(res) >= numDesiredResults {
+			return res, nil
+		}
+	}
+
+	return res, search.ErrFewerResultsThanDesired
+}
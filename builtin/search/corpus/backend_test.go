@@ -0,0 +1,158 @@
+package corpus
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mccurdyc/neighbor/sdk/corpus"
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// newArchive writes an archive containing a project for each name to dir.
+func newArchive(t *testing.T, dir string, names ...string) string {
+	t.Helper()
+
+	var entries []corpus.Entry
+	for _, name := range names {
+		src := filepath.Join(dir, "src", name)
+		if err := os.MkdirAll(src, 0755); err != nil {
+			t.Fatalf("failed to create directory: %+v", err)
+		}
+
+		if err := ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %+v", err)
+		}
+
+		e, err := corpus.NewEntry(name, "https://github.com/"+name, "abc", time.Now(), src)
+		if err != nil {
+			t.Fatalf("failed to create entry: %+v", err)
+		}
+		entries = append(entries, e)
+	}
+
+	archive := filepath.Join(dir, "corpus.tar")
+	if err := corpus.WriteFile(archive, &corpus.Manifest{Projects: entries}); err != nil {
+		t.Fatalf("failed to write archive: %+v", err)
+	}
+
+	return archive
+}
+
+func Test_Search(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "neighbor-corpus-search")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	archive := newArchive(t, tmp, "owner/a", "owner/b", "other/c")
+
+	type input struct {
+		conf  *search.BackendConfig
+		query string
+		num   int
+	}
+
+	type want struct {
+		names      []string
+		ids        []string
+		factoryErr error
+		err        error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"all": {
+			input: input{
+				conf: &search.BackendConfig{Config: map[string]string{"archive": archive}},
+				num:  3,
+			},
+			want: want{
+				names: []string{"other/c", "owner/a", "owner/b"},
+				ids:   []string{"github.com/other/c@abc", "github.com/owner/a@abc", "github.com/owner/b@abc"},
+			},
+		},
+
+		"pattern": {
+			input: input{
+				conf:  &search.BackendConfig{Config: map[string]string{"archive": archive}},
+				query: "owner/*",
+				num:   3,
+			},
+			want: want{
+				names: []string{"owner/a", "owner/b"},
+				ids:   []string{"github.com/owner/a@abc", "github.com/owner/b@abc"},
+				err:   search.ErrFewerResultsThanDesired,
+			},
+		},
+
+		"missing_archive": {
+			input: input{
+				conf: &search.BackendConfig{},
+			},
+			want: want{
+				factoryErr: fmt.Errorf("archive cannot be empty"),
+			},
+		},
+
+		"unsupported_search_method": {
+			input: input{
+				conf: &search.BackendConfig{SearchMethod: search.Code},
+			},
+			want: want{
+				factoryErr: fmt.Errorf("only the Project search method is supported"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			b, gotErr := Factory(context.TODO(), tt.input.conf)
+			if ok := errorCmp(gotErr, tt.want.factoryErr); !ok {
+				t.Fatalf("Factory() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.factoryErr)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			projects, gotErr := b.Search(context.TODO(), tt.input.query, tt.input.num)
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Search() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			var got, ids []string
+			for _, p := range projects {
+				got = append(got, p.Name())
+				ids = append(ids, project.IDOf(p).String())
+			}
+
+			if diff := cmp.Diff(tt.want.names, got); diff != "" {
+				t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.want.ids, ids); diff != "" {
+				t.Errorf("Search() IDs mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,12 +2,20 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	nethttp "net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
+	"github.com/mccurdyc/neighbor/builtin/internal/githubapp"
+	"github.com/mccurdyc/neighbor/sdk/auth"
 	"github.com/mccurdyc/neighbor/sdk/project"
 	"github.com/mccurdyc/neighbor/sdk/search"
+	"golang.org/x/oauth2"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 )
@@ -24,16 +32,39 @@ const (
 )
 
 // ErrFewerResultsThanDesired is used to indicate that it was not possible to fulfill
-// the request from the user (i.e., could not find the number of results specified
-// by the user).
-//
-// This is important to specify because, for example, in research you might want
-// to guarantee that you are analyzing _exactly_ the number of projects specifed
-// or the search query may need to be tweaked.
-var ErrFewerResultsThanDesired = fmt.Errorf("contains fewer results than desired")
+// the request from the user. It is kept for backwards compatibility and is the
+// same value as search.ErrFewerResultsThanDesired.
+var ErrFewerResultsThanDesired = search.ErrFewerResultsThanDesired
 
 // Factory is the factory function to be used to create a GitHub search backend.
+//
+// API requests are authenticated according to conf.AuthMethod, unless an http
+// client is explicitly specified with conf.Client. GitHub Enterprise Server is
+// searched when conf.BaseURL is set, e.g., https://github.example.com or
+// https://github.example.com/api/v3/.
+//
+// API responses are cached on disk when the "cache_dir" config value is set.
+// Cached responses are reused without a request for the "cache_ttl" config value
+// (e.g., 1h, defaults to 0) and are revalidated with conditional requests after.
+//
+// Forks are collapsed onto the repository that they were forked from when the
+// "collapse_forks" config value is true and repositories with the same tree hash
+// at their latest commit (e.g., mirrors) are collapsed onto the first one found
+// when the "dedupe_content" config value is true. The collapsed repositories are
+// listed as duplicates in the metadata of the remaining one.
+//
+// The "token" auth method accepts a pool of comma-separated tokens in the "token"
+// or "tokens" config values, which are rotated to spread the API quota.
+// Besides "basic" and "token", the "github_app" auth method authenticates as an
+// installation of a GitHub App (see githubapp.FromConfig for its config values)
+// and the "credentials" auth method uses the credential that conf.Credentials has
+// for the GitHub host, if any.
 func Factory(ctx context.Context, conf *search.BackendConfig) (search.Backend, error) {
+	conf, err := resolveCredentials(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(conf.AuthMethod) == 0 {
 		// auth method required for GitHub code search - https://developer.github.com/v3/search/#search-code
 		if conf.SearchMethod == search.Code {
@@ -77,28 +108,75 @@ func Factory(ctx context.Context, conf *search.BackendConfig) (search.Backend, e
 	}
 
 	if strings.EqualFold(conf.AuthMethod, "token") {
-		token := conf.Config["token"]
+		tokens := splitTokens(conf.Config["token"], conf.Config["tokens"])
 
-		if len(token) == 0 {
+		if len(tokens) == 0 {
 			return nil, fmt.Errorf("token required for token auth")
 		}
 		auth = &http.BasicAuth{
 			Username: "null", // this can't be an empty string
-			Password: token,
+			Password: tokens[0],
+		}
+	}
+
+	var ts oauth2.TokenSource
+	if strings.EqualFold(conf.AuthMethod, "github_app") {
+		baseURL := githubapp.DefaultBaseURL
+		if len(conf.BaseURL) != 0 {
+			if baseURL, err = enterpriseURL(conf.BaseURL, "api/v3/"); err != nil {
+				return nil, fmt.Errorf("invalid base url: %+v", err)
+			}
+		}
+
+		if ts, err = githubapp.FromConfig(conf.Config, baseURL, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var collapseForks, dedupeContent bool
+	if v := conf.Config["collapse_forks"]; len(v) != 0 {
+		if collapseForks, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("collapse_forks must be a boolean")
+		}
+	}
+
+	if v := conf.Config["dedupe_content"]; len(v) != 0 {
+		if dedupeContent, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("dedupe_content must be a boolean")
 		}
 	}
 
-	c := github.NewClient(conf.Client)
+	var base nethttp.RoundTripper
+	if dir := conf.Config["cache_dir"]; len(dir) != 0 {
+		var ttl time.Duration
+		if v := conf.Config["cache_ttl"]; len(v) != 0 {
+			if ttl, err = time.ParseDuration(v); err != nil || ttl < 0 {
+				return nil, fmt.Errorf("cache_ttl must be a non-negative duration")
+			}
+		}
+
+		if base, err = newCacheTransport(dir, ttl, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := newClient(conf, newHTTPClient(conf, ts, base))
+	if err != nil {
+		return nil, err
+	}
 
 	return &Backend{
 		auth: auth,
 		githubClient: Client{
 			SearchService:     c.Search,
 			RepositoryService: c.Repositories,
+			Client:            c,
 		},
 		searchMethod:       conf.SearchMethod,
 		searchMethodEntity: entity,
 		maxPageSize:        maxPageSize,
+		collapseForks:      collapseForks,
+		dedupeContent:      dedupeContent,
 	}, nil
 }
 
@@ -109,6 +187,8 @@ type Backend struct {
 	searchMethod       search.Method
 	searchMethodEntity searchMethodEntity
 	maxPageSize        int
+	collapseForks      bool
+	dedupeContent      bool
 }
 
 // Search is the search function for searching GitHub for projects, code snippets,
@@ -116,7 +196,12 @@ type Backend struct {
 func (b *Backend) Search(ctx context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
 	res := make([]project.Backend, 0, numDesiredResults)
 
-	var page int
+	d := &deduper{
+		client:  b.githubClient,
+		forks:   b.collapseForks,
+		content: b.dedupeContent,
+	}
+
 	opts := github.SearchOptions{
 		ListOptions: github.ListOptions{
 			PerPage: pageSize(numDesiredResults, b.maxPageSize),
@@ -146,7 +231,14 @@ func (b *Backend) Search(ctx context.Context, query string, numDesiredResults in
 		}
 
 		for _, r := range searchRes {
-			res = append(res, r)
+			if d.enabled() {
+				if res, err = d.add(ctx, res, r); err != nil {
+					return nil, err
+				}
+			} else {
+				res = append(res, r)
+			}
+
 			if len(res) >= numDesiredResults {
 				return res, nil
 			}
@@ -156,6 +248,132 @@ func (b *Backend) Search(ctx context.Context, query string, numDesiredResults in
 			return res, ErrFewerResultsThanDesired
 		}
 
-		opts.ListOptions.Page = page + 1
+		opts.ListOptions.Page = resp.NextPage
+	}
+}
+
+// resolveCredentials returns a copy of conf that uses the "token" or "basic" auth
+// method for the credential found by conf.Credentials when its auth method is
+// "credentials". The copy is unauthenticated when no credential is found.
+func resolveCredentials(ctx context.Context, conf *search.BackendConfig) (*search.BackendConfig, error) {
+	if !strings.EqualFold(conf.AuthMethod, "credentials") {
+		return conf, nil
+	}
+
+	if conf.Credentials == nil {
+		return nil, fmt.Errorf("credentials provider required for credentials auth")
+	}
+
+	host := "github.com"
+	if len(conf.BaseURL) != 0 {
+		u, err := url.Parse(conf.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base url: %+v", err)
+		}
+		host = u.Hostname()
+	}
+
+	resolved := *conf
+	resolved.AuthMethod = ""
+
+	cred, err := conf.Credentials.Credential(ctx, host)
+	if errors.Is(err, auth.ErrNoCredential) {
+		return &resolved, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential for '%s': %+v", host, err)
+	}
+
+	resolved.AuthMethod, resolved.Config = cred.Method()
+	for k, v := range conf.Config {
+		if _, ok := resolved.Config[k]; !ok {
+			resolved.Config[k] = v
+		}
+	}
+
+	return &resolved, nil
+}
+
+// newHTTPClient returns conf.Client if it is set or an http client that
+// authenticates API requests according to conf.AuthMethod before passing them
+// to base (e.g., the cache). The credentials must already be validated and ts is
+// the token source of a GitHub App installation.
+func newHTTPClient(conf *search.BackendConfig, ts oauth2.TokenSource, base nethttp.RoundTripper) *nethttp.Client {
+	if conf.Client != nil {
+		return conf.Client
+	}
+
+	switch strings.ToLower(conf.AuthMethod) {
+	case "basic":
+		tp := &github.BasicAuthTransport{
+			Username:  conf.Config["username"],
+			Password:  conf.Config["password"],
+			Transport: base,
+		}
+		return tp.Client()
+	case "token":
+		tokens := splitTokens(conf.Config["token"], conf.Config["tokens"])
+		if len(tokens) > 1 {
+			return &nethttp.Client{Transport: newTokenPool(tokens, base)}
+		}
+
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tokens[0]})
+		return &nethttp.Client{Transport: &oauth2.Transport{Source: ts, Base: base}}
+	case "github_app":
+		return &nethttp.Client{Transport: &oauth2.Transport{Source: ts, Base: base}}
+	}
+
+	return &nethttp.Client{Transport: base}
+}
+
+// newClient returns a client for github.com or, if conf.BaseURL is set, for a
+// GitHub Enterprise Server instance.
+func newClient(conf *search.BackendConfig, httpClient *nethttp.Client) (*github.Client, error) {
+	if len(conf.BaseURL) == 0 {
+		if len(conf.UploadURL) != 0 {
+			return nil, fmt.Errorf("base url required with upload url")
+		}
+
+		return github.NewClient(httpClient), nil
+	}
+
+	baseURL, err := enterpriseURL(conf.BaseURL, "api/v3/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid base url: %+v", err)
+	}
+
+	uploadURL := conf.UploadURL
+	if len(uploadURL) == 0 {
+		uploadURL = strings.TrimSuffix(baseURL, "api/v3/")
+	}
+
+	uploadURL, err = enterpriseURL(uploadURL, "api/uploads/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid upload url: %+v", err)
+	}
+
+	return github.NewEnterpriseClient(baseURL, uploadURL, httpClient)
+}
+
+// enterpriseURL returns the API url of a GitHub Enterprise Server instance,
+// appending the API path (e.g., api/v3/) to raw if it only specifies the host.
+func enterpriseURL(raw string, apiPath string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported scheme '%s'", u.Scheme)
 	}
+
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	if !strings.HasSuffix(u.Path, "/api/v3/") && !strings.HasSuffix(u.Path, "/api/uploads/") {
+		u.Path += apiPath
+	}
+
+	return u.String(), nil
 }
@@ -2,7 +2,14 @@ package github
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
 	"strconv"
 	"testing"
@@ -10,6 +17,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-github/github"
 	githubProject "github.com/mccurdyc/neighbor/builtin/project/github"
+	"github.com/mccurdyc/neighbor/sdk/auth"
 	"github.com/mccurdyc/neighbor/sdk/project"
 	"github.com/mccurdyc/neighbor/sdk/search"
 	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
@@ -68,6 +76,18 @@ func Test_Factory(t *testing.T) {
 			},
 		},
 
+		"invalid_collapse_forks": {
+			input: input{
+				conf: &search.BackendConfig{
+					SearchMethod: search.Project,
+					Config:       map[string]string{"collapse_forks": "sometimes"},
+				},
+			},
+			want: want{
+				err: fmt.Errorf("collapse_forks must be a boolean"),
+			},
+		},
+
 		"missing_meta_entity_meta_search": {
 			input: input{
 				conf: &search.BackendConfig{
@@ -219,6 +239,24 @@ func (m *mockClient) ListCommits(ctx context.Context, owner string, repo string,
 	return m.commits, m.response, m.err
 }
 
+// Get fetches a repository.
+func (m *mockClient) Get(ctx context.Context, owner string, repo string) (*github.Repository, *github.Response, error) {
+	if m.err != nil {
+		return nil, m.response, m.err
+	}
+
+	if m.repositories != nil {
+		for _, r := range m.repositories.Repositories {
+			if r.GetOwner().GetLogin() == owner && r.GetName() == repo {
+				r := r
+				return &r, m.response, nil
+			}
+		}
+	}
+
+	return nil, m.response, fmt.Errorf("repository '%s/%s' not found", owner, repo)
+}
+
 func newMockClient(maxPageSize int, numCommits int, duplicateResults bool, nextPage bool, err error) Client {
 	repos := make([]github.Repository, 0, maxPageSize)
 	codeRes := make([]github.CodeResult, 0, maxPageSize)
@@ -227,28 +265,38 @@ func newMockClient(maxPageSize int, numCommits int, duplicateResults bool, nextP
 		name := strconv.Itoa(i)
 		fullname := fmt.Sprintf("repo/%s", name)
 		cloneURL := fmt.Sprintf("cloneurl%d.git", i)
-		ownerName := fmt.Sprintf("owner%d", i)
+		ownerLogin := fmt.Sprintf("owner%d", i)
+		size := i
 
 		repo := github.Repository{
 			Name:     &name,
 			FullName: &fullname,
 			CloneURL: &cloneURL,
+			Size:     &size,
 			Owner: &github.User{
-				Name: &ownerName,
+				Login: &ownerLogin,
 			},
 		}
 
+		// the repositories of code results only include their names and urls.
+		codeRepo := github.Repository{
+			Name:     &name,
+			FullName: &fullname,
+			CloneURL: &cloneURL,
+			Owner:    repo.Owner,
+		}
+
 		repos = append(repos, repo)
 		codeRes = append(codeRes,
 			github.CodeResult{
-				Repository: &repo,
+				Repository: &codeRepo,
 			})
 
 		if duplicateResults {
 			repos = append(repos, repo)
 			codeRes = append(codeRes,
 				github.CodeResult{
-					Repository: &repo,
+					Repository: &codeRepo,
 				})
 		}
 	}
@@ -272,6 +320,9 @@ func newMockClient(maxPageSize int, numCommits int, duplicateResults bool, nextP
 
 	return Client{
 		RepositoryService: &mockClient{
+			repositories: &github.RepositoriesSearchResult{
+				Repositories: repos,
+			},
 			commits:  commits,
 			response: &resp,
 			err:      err,
@@ -303,6 +354,7 @@ func generateWantProjects(t *testing.T, name string, numDesiredResults int, maxP
 				Name:           fmt.Sprintf("repo/%d", i),
 				Version:        "sha0", // we always want the latest commit
 				SourceLocation: fmt.Sprintf("cloneurl%d.git", i),
+				Metadata:       &project.Metadata{Size: int64(i) * 1024}, // GitHub reports sizes in kilobytes
 			})
 			if err != nil {
 				t.Errorf("%s(): failed to create project: %+v", name, err)
@@ -518,3 +570,266 @@ func compareBackend(t *testing.T, name string, want *Backend, got search.Backend
 		t.Errorf("%s() mismatched search method entity (-want +got):\n%s", name, diff)
 	}
 }
+
+func Test_newClient(t *testing.T) {
+	type want struct {
+		baseURL   string
+		uploadURL string
+		err       error
+	}
+
+	var tests = map[string]struct {
+		input *search.BackendConfig
+		want  want
+	}{
+		"github_com": {
+			input: &search.BackendConfig{},
+			want: want{
+				baseURL:   "https://api.github.com/",
+				uploadURL: "https://uploads.github.com/",
+			},
+		},
+
+		"enterprise_host": {
+			input: &search.BackendConfig{BaseURL: "https://github.example.com"},
+			want: want{
+				baseURL:   "https://github.example.com/api/v3/",
+				uploadURL: "https://github.example.com/api/uploads/",
+			},
+		},
+
+		"enterprise_api_path": {
+			input: &search.BackendConfig{BaseURL: "https://github.example.com/api/v3"},
+			want: want{
+				baseURL:   "https://github.example.com/api/v3/",
+				uploadURL: "https://github.example.com/api/uploads/",
+			},
+		},
+
+		"enterprise_upload_url": {
+			input: &search.BackendConfig{
+				BaseURL:   "https://github.example.com/api/v3/",
+				UploadURL: "https://uploads.example.com",
+			},
+			want: want{
+				baseURL:   "https://github.example.com/api/v3/",
+				uploadURL: "https://uploads.example.com/api/uploads/",
+			},
+		},
+
+		"missing_base_url": {
+			input: &search.BackendConfig{UploadURL: "https://uploads.example.com"},
+			want: want{
+				err: fmt.Errorf("base url required with upload url"),
+			},
+		},
+
+		"unsupported_scheme": {
+			input: &search.BackendConfig{BaseURL: "ftp://github.example.com"},
+			want: want{
+				err: fmt.Errorf("invalid base url: unsupported scheme 'ftp'"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := newClient(tt.input, nil)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("newClient() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			if got.BaseURL.String() != tt.want.baseURL {
+				t.Errorf("newClient() mismatched base url: \n\tgot: '%+v'\n\twant: '%+v'", got.BaseURL, tt.want.baseURL)
+			}
+
+			if got.UploadURL.String() != tt.want.uploadURL {
+				t.Errorf("newClient() mismatched upload url: \n\tgot: '%+v'\n\twant: '%+v'", got.UploadURL, tt.want.uploadURL)
+			}
+		})
+	}
+}
+
+func Test_Search_enterprise(t *testing.T) {
+	mux := nethttp.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/api/v3/search/repositories", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprintf(w, `{"total_count": 1, "items": [{"name": "repo", "full_name": "owner/repo", "clone_url": "%s/owner/repo.git", "owner": {"login": "owner"}}]}`, srv.URL)
+	})
+
+	mux.HandleFunc("/api/v3/repos/owner/repo/commits", func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		fmt.Fprint(w, `[{"sha": "abc"}]`)
+	})
+
+	b, err := Factory(context.TODO(), &search.BackendConfig{
+		SearchMethod: search.Project,
+		BaseURL:      srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("Factory() unexpected error: %+v", err)
+	}
+
+	got, err := b.Search(context.TODO(), "query", 1)
+	if err != nil {
+		t.Fatalf("Search() unexpected error: %+v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("Search() mismatched number of projects: %d", len(got))
+	}
+
+	if want := srv.URL + "/owner/repo.git"; got[0].SourceLocation() != want {
+		t.Errorf("Search() mismatched source location: \n\tgot: '%+v'\n\twant: '%+v'", got[0].SourceLocation(), want)
+	}
+
+	if got[0].Version() != "abc" {
+		t.Errorf("Search() mismatched version: \n\tgot: '%+v'\n\twant: 'abc'", got[0].Version())
+	}
+}
+
+type headerTransport struct {
+	header string
+}
+
+func (t *headerTransport) RoundTrip(r *nethttp.Request) (*nethttp.Response, error) {
+	r.Header.Set("Authorization", t.header)
+	return nethttp.DefaultTransport.RoundTrip(r)
+}
+
+func Test_Factory_authentication(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %+v", err)
+	}
+
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var gotAuth string
+
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/api/v3/app/installations/42/access_tokens" {
+			w.WriteHeader(nethttp.StatusCreated)
+			fmt.Fprint(w, `{"token": "installation123", "expires_at": "2100-01-01T00:00:00Z"}`)
+			return
+		}
+
+		gotAuth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"total_count": 0, "items": []}`)
+	}))
+	defer srv.Close()
+
+	const env = "NEIGHBOR_TEST_TOKEN"
+	os.Setenv(env, "token123")
+	defer os.Unsetenv(env)
+
+	var tests = map[string]struct {
+		input *search.BackendConfig
+		want  string
+	}{
+		"unauthenticated": {
+			input: &search.BackendConfig{},
+			want:  "",
+		},
+
+		"token_auth": {
+			input: &search.BackendConfig{
+				AuthMethod: "token",
+				Config:     map[string]string{"token": "token123"},
+			},
+			want: "Bearer token123",
+		},
+
+		"basic_auth": {
+			input: &search.BackendConfig{
+				AuthMethod: "basic",
+				Config:     map[string]string{"username": "username123", "password": "password123"},
+			},
+			want: "Basic dXNlcm5hbWUxMjM6cGFzc3dvcmQxMjM=",
+		},
+
+		"credentials": {
+			input: &search.BackendConfig{
+				AuthMethod:  "credentials",
+				Credentials: auth.Chain{&auth.Env{Name: "NEIGHBOR_TEST_UNSET"}, &auth.Env{Name: env, Hosts: []string{"127.0.0.1"}}},
+			},
+			want: "Bearer token123",
+		},
+
+		"credentials_none": {
+			input: &search.BackendConfig{
+				AuthMethod:  "credentials",
+				Credentials: auth.Chain{},
+			},
+			want: "",
+		},
+
+		"credentials_other_host": {
+			input: &search.BackendConfig{
+				AuthMethod:  "credentials",
+				Credentials: auth.Chain{&auth.Env{Name: env}},
+			},
+			want: "",
+		},
+
+		"token_pool": {
+			input: &search.BackendConfig{
+				AuthMethod: "token",
+				Config:     map[string]string{"tokens": "token123,token456"},
+			},
+			want: "Bearer token123",
+		},
+
+		"github_app": {
+			input: &search.BackendConfig{
+				AuthMethod: "github_app",
+				Config:     map[string]string{"app_id": "7", "installation_id": "42", "private_key": string(pemKey)},
+			},
+			want: "token installation123",
+		},
+
+		"explicit_client": {
+			input: &search.BackendConfig{
+				AuthMethod: "token",
+				Config:     map[string]string{"token": "token123"},
+				Client:     &nethttp.Client{Transport: &headerTransport{header: "custom"}},
+			},
+			want: "custom",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			gotAuth = ""
+			tt.input.SearchMethod = search.Project
+			tt.input.BaseURL = srv.URL
+
+			b, err := Factory(context.TODO(), tt.input)
+			if err != nil {
+				t.Fatalf("Factory() unexpected error: %+v", err)
+			}
+
+			b.Search(context.TODO(), "query", 1)
+
+			if gotAuth != tt.want {
+				t.Errorf("Factory() mismatched authorization: \n\tgot: '%+v'\n\twant: '%+v'", gotAuth, tt.want)
+			}
+		})
+	}
+}
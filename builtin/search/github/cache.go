@@ -0,0 +1,173 @@
+package github
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheTransport is an http.RoundTripper that stores GET responses on disk, keyed
+// by url and auth identity (i.e., a hash of the Authorization header), so that
+// credentials never share cached responses.
+//
+// A cached response is reused without a request until it is older than ttl.
+// After that, it is revalidated with If-None-Match and If-Modified-Since, and
+// a 304 Not Modified response, which does not count against the rate limit, is
+// answered from the cache.
+// https://developer.github.com/v3/#conditional-requests
+type cacheTransport struct {
+	dir  string
+	ttl  time.Duration
+	base nethttp.RoundTripper
+	now  func() time.Time
+}
+
+// newCacheTransport is a constructor that returns a pointer to a cacheTransport
+// that stores responses in dir. If base is nil, http.DefaultTransport is used.
+func newCacheTransport(dir string, ttl time.Duration, base nethttp.RoundTripper) (*cacheTransport, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %+v", err)
+	}
+
+	if base == nil {
+		base = nethttp.DefaultTransport
+	}
+
+	return &cacheTransport{
+		dir:  dir,
+		ttl:  ttl,
+		base: base,
+		now:  time.Now,
+	}, nil
+}
+
+// RoundTrip answers r from the cache when possible.
+func (c *cacheTransport) RoundTrip(r *nethttp.Request) (*nethttp.Response, error) {
+	if r.Method != nethttp.MethodGet {
+		return c.base.RoundTrip(r)
+	}
+
+	p := c.path(r)
+	cached, storedAt := c.load(p, r)
+
+	if cached != nil && c.now().Sub(storedAt) < c.ttl {
+		// the quota reported when the response was stored is outdated.
+		for k := range cached.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-ratelimit-") {
+				cached.Header.Del(k)
+			}
+		}
+		return cached, nil
+	}
+
+	req := r
+	if cached != nil {
+		req = r.Clone(r.Context())
+		if etag := cached.Header.Get("ETag"); len(etag) != 0 {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); len(lm) != 0 {
+			req.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == nethttp.StatusNotModified && cached != nil {
+		resp.Body.Close()
+
+		for k, v := range resp.Header {
+			cached.Header[k] = v
+		}
+
+		c.store(p, cached)
+		return cached, nil
+	}
+
+	if resp.StatusCode == nethttp.StatusOK && (len(resp.Header.Get("ETag")) != 0 || len(resp.Header.Get("Last-Modified")) != 0 || c.ttl > 0) {
+		c.store(p, resp)
+	}
+
+	return resp, nil
+}
+
+// path returns the path of the cached response for r.
+func (c *cacheTransport) path(r *nethttp.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s", r.Method, r.URL.String(), r.Header.Get("Authorization"))
+
+	return filepath.Join(c.dir, hex.EncodeToString(h.Sum(nil)))
+}
+
+// load returns the cached response at p and when it was stored, if any.
+func (c *cacheTransport) load(p string, r *nethttp.Request) (*nethttp.Response, time.Time) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	resp, err := nethttp.ReadResponse(bufio.NewReader(bytes.NewReader(b)), r)
+	if err != nil {
+		return nil, time.Time{}
+	}
+
+	return resp, info.ModTime()
+}
+
+// store writes resp to p, leaving resp readable. Failures are ignored because
+// the cache is only an optimization.
+func (c *cacheTransport) store(p string, resp *nethttp.Response) {
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+
+	stored := *resp
+	stored.Body = ioutil.NopCloser(bytes.NewReader(body))
+	stored.ContentLength = int64(len(body))
+	stored.TransferEncoding = nil
+
+	var buf bytes.Buffer
+	if err := stored.Write(&buf); err != nil {
+		return
+	}
+
+	tmp, err := ioutil.TempFile(c.dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+
+	now := c.now()
+	os.Chtimes(tmp.Name(), now, now)
+
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
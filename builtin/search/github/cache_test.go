@@ -0,0 +1,132 @@
+package github
+
+import (
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_cacheTransport(t *testing.T) {
+	type request struct {
+		auth string
+		// advance is how far the clock is moved before the request.
+		advance time.Duration
+	}
+
+	type input struct {
+		ttl      time.Duration
+		requests []request
+	}
+
+	type want struct {
+		// conditional is whether each request that reached the server was conditional.
+		conditional []bool
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"revalidated": {
+			input: input{
+				requests: []request{{auth: "token a"}, {auth: "token a"}},
+			},
+			want: want{
+				conditional: []bool{false, true},
+			},
+		},
+
+		"fresh": {
+			input: input{
+				ttl:      time.Hour,
+				requests: []request{{auth: "token a"}, {auth: "token a"}},
+			},
+			want: want{
+				conditional: []bool{false},
+			},
+		},
+
+		"stale": {
+			input: input{
+				ttl:      time.Hour,
+				requests: []request{{auth: "token a"}, {auth: "token a", advance: 2 * time.Hour}},
+			},
+			want: want{
+				conditional: []bool{false, true},
+			},
+		},
+
+		"separate_identities": {
+			input: input{
+				ttl:      time.Hour,
+				requests: []request{{auth: "token a"}, {auth: "token b"}},
+			},
+			want: want{
+				conditional: []bool{false, false},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var got []bool
+
+			srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+				conditional := r.Header.Get("If-None-Match") == `"v1"`
+				got = append(got, conditional)
+
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("X-RateLimit-Remaining", "10")
+				if conditional {
+					w.WriteHeader(nethttp.StatusNotModified)
+					return
+				}
+
+				fmt.Fprint(w, `{"total_count": 0}`)
+			}))
+			defer srv.Close()
+
+			dir, err := ioutil.TempDir("", "neighbor-cache")
+			if err != nil {
+				t.Fatalf("failed to create directory: %+v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			c, err := newCacheTransport(dir, tt.input.ttl, nil)
+			if err != nil {
+				t.Fatalf("newCacheTransport() unexpected error: %+v", err)
+			}
+
+			now := time.Now()
+			for i, r := range tt.input.requests {
+				now = now.Add(r.advance)
+				c.now = func() time.Time { return now }
+
+				req, _ := nethttp.NewRequest(nethttp.MethodGet, srv.URL+"/search/repositories?q=neighbor", nil)
+				req.Header.Set("Authorization", r.auth)
+
+				resp, err := c.RoundTrip(req)
+				if err != nil {
+					t.Fatalf("RoundTrip() unexpected error: %+v", err)
+				}
+
+				body, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil || resp.StatusCode != nethttp.StatusOK || string(body) != `{"total_count": 0}` {
+					t.Errorf("RoundTrip() request %d mismatched response: %d '%s' (%+v)", i, resp.StatusCode, body, err)
+				}
+			}
+
+			if diff := cmp.Diff(tt.want.conditional, got); diff != "" {
+				t.Errorf("RoundTrip() mismatched requests (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -28,4 +28,6 @@ type SearchService interface {
 type RepositoryService interface {
 	// ListCommits lists the commits for a specific repository.
 	ListCommits(context.Context, string, string, *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error)
+	// Get fetches a repository, including the parent and source of a fork.
+	Get(context.Context, string, string) (*github.Repository, *github.Response, error)
 }
@@ -0,0 +1,134 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	githubProject "github.com/mccurdyc/neighbor/builtin/project/github"
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+// deduper collapses search results that are the same project onto a single
+// result, whose metadata lists the others as duplicates.
+type deduper struct {
+	client Client
+	// forks is whether forks are collapsed onto their upstream repository.
+	forks bool
+	// content is whether repositories with the same tree hash at their latest
+	// commit, e.g., mirrors, are collapsed onto the first one found.
+	content bool
+}
+
+func (d *deduper) enabled() bool {
+	return d.forks || d.content
+}
+
+// add adds p to res, unless it is the same project as one of res, in which case
+// that project lists p as a duplicate instead.
+func (d *deduper) add(ctx context.Context, res []project.Backend, p project.Backend) ([]project.Backend, error) {
+	if d.forks {
+		upstream, err := d.upstream(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		p = upstream
+	}
+
+	for i, r := range res {
+		if d.same(r, p) {
+			merged, err := addDuplicates(ctx, r, p)
+			if err != nil {
+				return nil, err
+			}
+
+			res[i] = merged
+			return res, nil
+		}
+	}
+
+	return append(res, p), nil
+}
+
+func (d *deduper) same(a project.Backend, b project.Backend) bool {
+	if project.IDOf(a).Key() == project.IDOf(b).Key() {
+		return true
+	}
+
+	if !d.content {
+		return false
+	}
+
+	tree := project.MetadataOf(a).Attributes["tree_sha"]
+	return len(tree) != 0 && tree == project.MetadataOf(b).Attributes["tree_sha"]
+}
+
+// upstream returns the repository that p is a fork of, with p listed as a
+// duplicate, or p if it is not a fork.
+func (d *deduper) upstream(ctx context.Context, p project.Backend) (project.Backend, error) {
+	if !project.MetadataOf(p).Fork {
+		return p, nil
+	}
+
+	parts := strings.SplitN(p.Name(), "/", 2)
+	if len(parts) != 2 {
+		return p, nil
+	}
+
+	// search results do not include the parent or source of a fork.
+	repo, _, err := d.client.RepositoryService.Get(ctx, parts[0], parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fork ('%s'): %+v", p.Name(), err)
+	}
+
+	// the source is the root of a network of forks, whereas the parent may be a
+	// fork itself.
+	upstream := repo.GetSource()
+	if upstream == nil {
+		upstream = repo.GetParent()
+	}
+
+	if upstream == nil {
+		return p, nil
+	}
+
+	u, err := newProject(ctx, d.client, upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	return addDuplicates(ctx, u, p)
+}
+
+// addDuplicates returns a copy of p that lists dup, and the duplicates of dup, as
+// duplicates. The rest of the configuration and metadata of p, e.g., its size, is
+// kept.
+func addDuplicates(ctx context.Context, p project.Backend, dup project.Backend) (project.Backend, error) {
+	m := project.MetadataOf(p).Clone()
+
+	for _, name := range append([]string{dup.Name()}, project.MetadataOf(dup).Duplicates...) {
+		if name == p.Name() || containsString(m.Duplicates, name) {
+			continue
+		}
+
+		m.Duplicates = append(m.Duplicates, name)
+	}
+
+	return githubProject.Factory(ctx, &project.BackendConfig{
+		Name:           p.Name(),
+		Version:        p.Version(),
+		SourceLocation: p.SourceLocation(),
+		RetrievalFunc:  p.RetrievalFunc(),
+		Metadata:       m,
+	})
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,292 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/github"
+
+	githubProject "github.com/mccurdyc/neighbor/builtin/project/github"
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// mockRepositories is a RepositoryService of repositories by full name.
+type mockRepositories struct {
+	repos map[string]*github.Repository
+	trees map[string]string
+}
+
+func (m *mockRepositories) ListCommits(ctx context.Context, owner string, repo string, opts *github.CommitsListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	name := fmt.Sprintf("%s/%s", owner, repo)
+	return []*github.RepositoryCommit{{
+		SHA:    github.String("sha-" + name),
+		Commit: &github.Commit{Tree: &github.Tree{SHA: github.String(m.trees[name])}},
+	}}, nil, nil
+}
+
+func (m *mockRepositories) Get(ctx context.Context, owner string, repo string) (*github.Repository, *github.Response, error) {
+	r, ok := m.repos[fmt.Sprintf("%s/%s", owner, repo)]
+	if !ok {
+		return nil, nil, fmt.Errorf("not found")
+	}
+
+	return r, nil, nil
+}
+
+// newRepo returns a repository as the search API returns it, which only includes
+// the login of the owner.
+func newRepo(owner string, name string, fork bool) *github.Repository {
+	return &github.Repository{
+		Name:     github.String(name),
+		FullName: github.String(fmt.Sprintf("%s/%s", owner, name)),
+		CloneURL: github.String(fmt.Sprintf("https://github.com/%s/%s.git", owner, name)),
+		Owner:    &github.User{Login: github.String(owner)},
+		Fork:     github.Bool(fork),
+	}
+}
+
+func Test_Search_dedupe(t *testing.T) {
+	upstream := newRepo("upstream", "tool", false)
+	forkA := newRepo("a", "tool", true)
+	forkB := newRepo("b", "tool", true)
+	mirror := newRepo("mirror", "tool-mirror", false)
+	other := newRepo("other", "thing", false)
+
+	forkADetails := newRepo("a", "tool", true)
+	forkADetails.Source = upstream
+	forkBDetails := newRepo("b", "tool", true)
+	forkBDetails.Parent = forkA
+	forkBDetails.Source = upstream
+
+	repos := &mockRepositories{
+		repos: map[string]*github.Repository{
+			"a/tool": forkADetails,
+			"b/tool": forkBDetails,
+		},
+		trees: map[string]string{
+			"upstream/tool":      "tree1",
+			"mirror/tool-mirror": "tree1",
+			"other/thing":        "tree2",
+		},
+	}
+
+	results := []github.Repository{*forkA, *upstream, *forkB, *mirror, *other}
+
+	type input struct {
+		backend *Backend
+	}
+
+	type want struct {
+		projects map[string][]string
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"disabled": {
+			input: input{backend: &Backend{}},
+			want: want{projects: map[string][]string{
+				"a/tool":             nil,
+				"upstream/tool":      nil,
+				"b/tool":             nil,
+				"mirror/tool-mirror": nil,
+				"other/thing":        nil,
+			}},
+		},
+
+		"collapse_forks": {
+			input: input{backend: &Backend{collapseForks: true}},
+			want: want{projects: map[string][]string{
+				"upstream/tool":      {"a/tool", "b/tool"},
+				"mirror/tool-mirror": nil,
+				"other/thing":        nil,
+			}},
+		},
+
+		"collapse_forks_and_content": {
+			input: input{backend: &Backend{collapseForks: true, dedupeContent: true}},
+			want: want{projects: map[string][]string{
+				"upstream/tool": {"a/tool", "b/tool", "mirror/tool-mirror"},
+				"other/thing":   nil,
+			}},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			tt.input.backend.githubClient = Client{
+				SearchService: &mockClient{
+					repositories: &github.RepositoriesSearchResult{Repositories: results},
+					response:     &github.Response{},
+				},
+				RepositoryService: repos,
+			}
+
+			got, _ := tt.input.backend.Search(context.TODO(), "query", 10)
+
+			projects := make(map[string][]string)
+			for _, p := range got {
+				projects[p.Name()] = project.MetadataOf(p).Duplicates
+			}
+
+			if diff := cmp.Diff(tt.want.projects, projects); diff != "" {
+				t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// pagedSearch is a SearchService that returns one page of repositories per
+// request. Page 0 is the first page, as it is for the GitHub API.
+type pagedSearch struct {
+	pages [][]github.Repository
+	// requested lists the page of each request.
+	requested []int
+}
+
+func (m *pagedSearch) Repositories(ctx context.Context, query string, opts *github.SearchOptions) (*github.RepositoriesSearchResult, *github.Response, error) {
+	m.requested = append(m.requested, opts.Page)
+
+	i := opts.Page - 1
+	if i < 0 {
+		i = 0
+	}
+
+	if i >= len(m.pages) {
+		return nil, nil, fmt.Errorf("page %d does not exist", opts.Page)
+	}
+
+	resp := &github.Response{}
+	if i+1 < len(m.pages) {
+		resp.NextPage = i + 2
+	}
+
+	return &github.RepositoriesSearchResult{Repositories: m.pages[i]}, resp, nil
+}
+
+func (m *pagedSearch) Code(ctx context.Context, query string, opts *github.SearchOptions) (*github.CodeSearchResult, *github.Response, error) {
+	return nil, nil, fmt.Errorf("not implemented")
+}
+
+func Test_Search_dedupe_pagination(t *testing.T) {
+	upstream := newRepo("upstream", "tool", false)
+	mirror := newRepo("mirror", "tool-mirror", false)
+	mirror2 := newRepo("mirror2", "tool-mirror", false)
+	other := newRepo("other", "thing", false)
+
+	repos := &mockRepositories{
+		trees: map[string]string{
+			"upstream/tool":       "tree1",
+			"mirror/tool-mirror":  "tree1",
+			"mirror2/tool-mirror": "tree1",
+			"other/thing":         "tree2",
+		},
+	}
+
+	type want struct {
+		projects  []string
+		requested []int
+		err       error
+	}
+
+	var tests = map[string]struct {
+		input [][]github.Repository
+		want  want
+	}{
+		"next_page_has_desired_results": {
+			input: [][]github.Repository{
+				{*upstream, *mirror},
+				{*mirror2},
+				{*other},
+			},
+			want: want{
+				projects:  []string{"upstream/tool", "other/thing"},
+				requested: []int{0, 2, 3},
+			},
+		},
+
+		"fewer_results_than_desired": {
+			input: [][]github.Repository{
+				{*upstream, *mirror},
+				{*mirror2},
+			},
+			want: want{
+				projects:  []string{"upstream/tool"},
+				requested: []int{0, 2},
+				err:       ErrFewerResultsThanDesired,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			s := &pagedSearch{pages: tt.input}
+			b := &Backend{
+				searchMethod:  search.Project,
+				dedupeContent: true,
+				githubClient: Client{
+					SearchService:     s,
+					RepositoryService: repos,
+				},
+			}
+
+			got, gotErr := b.Search(context.TODO(), "query", 2)
+
+			if gotErr != tt.want.err {
+				t.Errorf("Search() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			var projects []string
+			for _, p := range got {
+				projects = append(projects, p.Name())
+			}
+
+			if diff := cmp.Diff(tt.want.projects, projects); diff != "" {
+				t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.want.requested, s.requested); diff != "" {
+				t.Errorf("Search() requested pages mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_addDuplicates(t *testing.T) {
+	p, err := githubProject.Factory(context.TODO(), &project.BackendConfig{
+		Name:           "upstream/tool",
+		Version:        "abc123",
+		SourceLocation: "https://github.com/upstream/tool.git",
+		Metadata:       &project.Metadata{Size: 2048, Duplicates: []string{"a/tool"}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %+v", err)
+	}
+
+	dup := &mockProject{name: "b/tool", sourceLocation: "https://github.com/b/tool.git"}
+
+	got, err := addDuplicates(context.TODO(), p, dup)
+	if err != nil {
+		t.Fatalf("addDuplicates() unexpected error: %+v", err)
+	}
+
+	want := &project.Metadata{Size: 2048, Duplicates: []string{"a/tool", "b/tool"}}
+	if diff := cmp.Diff(want, project.MetadataOf(got)); diff != "" {
+		t.Errorf("addDuplicates() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got.Version() != p.Version() || got.SourceLocation() != p.SourceLocation() {
+		t.Errorf("addDuplicates() mismatched project: \n\tgot: '%s@%s'\n\twant: '%s@%s'", got.SourceLocation(), got.Version(), p.SourceLocation(), p.Version())
+	}
+
+	// the duplicates of p are not changed.
+	if diff := cmp.Diff([]string{"a/tool"}, project.MetadataOf(p).Duplicates); diff != "" {
+		t.Errorf("addDuplicates() changed the original project (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,158 @@
+package github
+
+import (
+	"fmt"
+	"math"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenPool is an http.RoundTripper that spreads API requests across several
+// tokens. Each request is authenticated with the token that has the most
+// remaining quota, as reported by the X-RateLimit-Remaining header. Exhausted
+// tokens are parked until their quota resets (i.e., X-RateLimit-Reset).
+//
+// GitHub tracks the search and core API quotas separately, so the quota of each
+// token is tracked per category.
+// https://developer.github.com/v3/#rate-limiting
+type tokenPool struct {
+	mu     sync.Mutex
+	tokens []*pooledToken
+	base   nethttp.RoundTripper
+	now    func() time.Time
+}
+
+type pooledToken struct {
+	value  string
+	quotas map[string]quota
+}
+
+type quota struct {
+	remaining int
+	reset     time.Time
+}
+
+// newTokenPool is a constructor that returns a pointer to a tokenPool. If base
+// is nil, http.DefaultTransport is used.
+func newTokenPool(tokens []string, base nethttp.RoundTripper) *tokenPool {
+	if base == nil {
+		base = nethttp.DefaultTransport
+	}
+
+	p := &tokenPool{
+		base: base,
+		now:  time.Now,
+	}
+
+	for _, t := range tokens {
+		p.tokens = append(p.tokens, &pooledToken{value: t, quotas: make(map[string]quota)})
+	}
+
+	return p
+}
+
+// RoundTrip authenticates r with the token with the most remaining quota.
+func (p *tokenPool) RoundTrip(r *nethttp.Request) (*nethttp.Response, error) {
+	category := rateCategory(r)
+
+	t, err := p.pick(category)
+	if err != nil {
+		return nil, err
+	}
+
+	// a RoundTripper must not modify the request.
+	r2 := r.Clone(r.Context())
+	r2.Header.Set("Authorization", "Bearer "+t.value)
+
+	resp, err := p.base.RoundTrip(r2)
+	if err != nil {
+		return nil, err
+	}
+
+	p.update(t, category, resp)
+	return resp, nil
+}
+
+// pick returns the token with the most remaining quota. Tokens without a known
+// quota are preferred so that every token is used.
+func (p *tokenPool) pick(category string) (*pooledToken, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+
+	var (
+		best      *pooledToken
+		bestQuota = -1
+		nextReset time.Time
+	)
+
+	for _, t := range p.tokens {
+		remaining := math.MaxInt32
+
+		if q, ok := t.quotas[category]; ok && now.Before(q.reset) {
+			remaining = q.remaining
+		}
+
+		if remaining <= 0 {
+			if q := t.quotas[category]; nextReset.IsZero() || q.reset.Before(nextReset) {
+				nextReset = q.reset
+			}
+			continue
+		}
+
+		if remaining > bestQuota {
+			best, bestQuota = t, remaining
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("all %d tokens exhausted the %s rate limit until %s", len(p.tokens), category, nextReset.Format(time.RFC3339))
+	}
+
+	return best, nil
+}
+
+// update records the quota reported by resp.
+func (p *tokenPool) update(t *pooledToken, category string, resp *nethttp.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+
+	reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t.quotas[category] = quota{remaining: remaining, reset: time.Unix(reset, 0)}
+}
+
+// rateCategory returns the rate limit category of r.
+func rateCategory(r *nethttp.Request) string {
+	if strings.Contains(r.URL.Path, "/search/") {
+		return "search"
+	}
+
+	return "core"
+}
+
+// splitTokens returns the non-empty, comma-separated tokens of values.
+func splitTokens(values ...string) []string {
+	var tokens []string
+	for _, v := range values {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); len(t) != 0 {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+
+	return tokens
+}
@@ -0,0 +1,167 @@
+package github
+
+import (
+	"fmt"
+	"io/ioutil"
+	nethttp "net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type roundTripFunc func(*nethttp.Request) (*nethttp.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *nethttp.Request) (*nethttp.Response, error) {
+	return f(r)
+}
+
+func Test_tokenPool(t *testing.T) {
+	now := time.Unix(1000, 0)
+	reset := now.Add(time.Hour)
+
+	type input struct {
+		// remaining is the quota reported after each request, by token.
+		remaining map[string][]int
+		requests  int
+		// advance is how far the clock is moved before the last request.
+		advance time.Duration
+	}
+
+	type want struct {
+		tokens []string
+		err    error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"most_remaining": {
+			input: input{
+				remaining: map[string][]int{"a": {10, 9}, "b": {100, 99}},
+				requests:  4,
+			},
+			want: want{
+				tokens: []string{"a", "b", "b", "b"},
+			},
+		},
+
+		"exhausted_parked": {
+			input: input{
+				remaining: map[string][]int{"a": {0}, "b": {5, 4, 3}},
+				requests:  4,
+			},
+			want: want{
+				tokens: []string{"a", "b", "b", "b"},
+			},
+		},
+
+		"all_exhausted": {
+			input: input{
+				remaining: map[string][]int{"a": {0}, "b": {0}},
+				requests:  3,
+			},
+			want: want{
+				tokens: []string{"a", "b"},
+				err:    fmt.Errorf("all 2 tokens exhausted the core rate limit until %s", reset.Format(time.RFC3339)),
+			},
+		},
+
+		"reset": {
+			input: input{
+				remaining: map[string][]int{"a": {0, 5000}, "b": {0}},
+				requests:  3,
+				advance:   2 * time.Hour,
+			},
+			want: want{
+				tokens: []string{"a", "b", "a"},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var got []string
+			calls := make(map[string]int)
+
+			base := roundTripFunc(func(r *nethttp.Request) (*nethttp.Response, error) {
+				token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+				got = append(got, token)
+
+				remaining := tt.input.remaining[token]
+				n := remaining[len(remaining)-1]
+				if calls[token] < len(remaining) {
+					n = remaining[calls[token]]
+				}
+				calls[token]++
+
+				header := make(nethttp.Header)
+				header.Set("X-RateLimit-Remaining", strconv.Itoa(n))
+				header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+				return &nethttp.Response{StatusCode: nethttp.StatusOK, Header: header, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+			})
+
+			p := newTokenPool([]string{"a", "b"}, base)
+			p.now = func() time.Time { return now }
+
+			var gotErr error
+			for i := 0; i < tt.input.requests; i++ {
+				if i == tt.input.requests-1 {
+					p.now = func() time.Time { return now.Add(tt.input.advance) }
+				}
+
+				req, _ := nethttp.NewRequest(nethttp.MethodGet, "https://api.github.com/repos/owner/name/commits", nil)
+				if _, gotErr = p.RoundTrip(req); gotErr != nil {
+					break
+				}
+
+				if req.Header.Get("Authorization") != "" {
+					t.Errorf("RoundTrip() modified the request")
+				}
+			}
+
+			if diff := cmp.Diff(tt.want.tokens, got); diff != "" {
+				t.Errorf("RoundTrip() mismatched tokens (-want +got):\n%s", diff)
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("RoundTrip() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
+
+func Test_rateCategory(t *testing.T) {
+	var tests = map[string]struct {
+		input string
+		want  string
+	}{
+		"search":            {input: "https://api.github.com/search/repositories", want: "search"},
+		"enterprise_search": {input: "https://github.example.com/api/v3/search/code", want: "search"},
+		"core":              {input: "https://api.github.com/repos/owner/name/commits", want: "core"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			req, _ := nethttp.NewRequest(nethttp.MethodGet, tt.input, nil)
+
+			if got := rateCategory(req); got != tt.want {
+				t.Errorf("rateCategory(%s): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want)
+			}
+		})
+	}
+}
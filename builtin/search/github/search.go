@@ -3,6 +3,9 @@ package github
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/github"
 	githubProject "github.com/mccurdyc/neighbor/builtin/project/github"
@@ -27,17 +30,18 @@ func searchRepositories(ctx context.Context, c Client, query string, numDesiredR
 
 	for _, repo := range searchRes.Repositories {
 		repo := repo
-		var version string
-		latest, _ := getLatestCommit(ctx, c, repo)
-		if latest != nil {
-			version = latest.GetSHA()
+
+		// search results do not include the parent of a fork, which is fetched so
+		// that forks of other results can be filtered out.
+		if repo.GetFork() && repo.Parent == nil {
+			full, _, err := c.RepositoryService.Get(ctx, repo.GetOwner().GetLogin(), repo.GetName())
+			if err != nil {
+				return res, resp, fmt.Errorf("failed to get fork ('%s'): %+v", repo.GetFullName(), err)
+			}
+			repo = *full
 		}
 
-		p, err := githubProject.Factory(ctx, &project.BackendConfig{
-			Name:           repo.GetFullName(),
-			Version:        version,
-			SourceLocation: getCloneURL(&repo),
-		})
+		p, err := newProject(ctx, c, &repo)
 		if err != nil {
 			continue
 		}
@@ -60,30 +64,27 @@ func searchCode(ctx context.Context, c Client, query string, numDesiredResults i
 		return res, resp, fmt.Errorf("empty code response")
 	}
 
+	seen := make(map[string]struct{})
 	for _, r := range searchRes.CodeResults {
-		repo := r.Repository
-
-		var version string
-
-		latest, _ := getLatestCommit(ctx, c, *repo)
-		if latest != nil {
-			version = latest.GetSHA()
+		// it is necessary to deduplicate for code search because the same repository
+		// will often have many occurences of a code statement and therefore, show up
+		// in many CodeResults.
+		name := strings.ToLower(r.Repository.GetFullName())
+		if _, ok := seen[name]; ok {
+			continue
 		}
+		seen[name] = struct{}{}
 
-		p, err := githubProject.Factory(ctx, &project.BackendConfig{
-			Name:           repo.GetFullName(),
-			Version:        version,
-			SourceLocation: getCloneURL(repo),
-		})
+		// the repositories of code results do not include their size, stars,
+		// license, language or timestamps, so the full repository is fetched.
+		repo, _, err := c.RepositoryService.Get(ctx, r.Repository.GetOwner().GetLogin(), r.Repository.GetName())
 		if err != nil {
-			return res, resp, err
+			return res, resp, fmt.Errorf("failed to get repository ('%s'): %+v", r.Repository.GetFullName(), err)
 		}
 
-		// it is necessary to deduplicate for code search because the same repository
-		// will often have many occurences of a code statement and therefore, show up
-		// in many CodeResults.
-		if contains(res, p) {
-			continue
+		p, err := newProject(ctx, c, repo)
+		if err != nil {
+			return res, resp, err
 		}
 
 		res = append(res, p)
@@ -92,6 +93,32 @@ func searchCode(ctx context.Context, c Client, query string, numDesiredResults i
 	return res, resp, nil
 }
 
+// newProject returns the project of a repository at its latest commit. The tree
+// hash of the latest commit is recorded in the "tree_sha" metadata attribute.
+func newProject(ctx context.Context, c Client, repo *github.Repository) (project.Backend, error) {
+	m := repoMetadata(repo)
+
+	var version string
+	latest, _ := getLatestCommit(ctx, c, *repo)
+	if latest != nil {
+		version = latest.GetSHA()
+
+		if tree := latest.GetCommit().GetTree().GetSHA(); len(tree) != 0 {
+			if m.Attributes == nil {
+				m.Attributes = make(map[string]string)
+			}
+			m.Attributes["tree_sha"] = tree
+		}
+	}
+
+	return githubProject.Factory(ctx, &project.BackendConfig{
+		Name:           repo.GetFullName(),
+		Version:        version,
+		SourceLocation: getCloneURL(repo),
+		Metadata:       m,
+	})
+}
+
 func searchMeta(ctx context.Context, entity searchMethodEntity, opts *github.SearchOptions) ([]project.Backend, *github.Response, error) {
 	switch entity {
 	case topic:
@@ -117,8 +144,10 @@ func searchTextMatch(ctx context.Context, opts *github.SearchOptions) ([]project
 	panic("not implemented")
 }
 
+// getLatestCommit returns the latest commit of repo. Search results only include
+// the login, not the name, of the owner of a repository.
 func getLatestCommit(ctx context.Context, c Client, repo github.Repository) (*github.RepositoryCommit, error) {
-	commits, _, err := c.RepositoryService.ListCommits(ctx, repo.GetOwner().GetName(), repo.GetName(), nil)
+	commits, _, err := c.RepositoryService.ListCommits(ctx, repo.GetOwner().GetLogin(), repo.GetName(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +159,12 @@ func getLatestCommit(ctx context.Context, c Client, repo github.Repository) (*gi
 	return commits[0], nil
 }
 
+// repoSize returns the size of a repository in bytes. GitHub reports sizes in
+// kilobytes.
+func repoSize(repo *github.Repository) int64 {
+	return int64(repo.GetSize()) * 1024
+}
+
 type URLRetriever interface {
 	GetCloneURL() string
 	GetHTMLURL() string
@@ -151,10 +186,70 @@ func getCloneURL(r URLRetriever) string {
 }
 
 func contains(projects []project.Backend, p project.Backend) bool {
+	key := project.IDOf(p).Key()
 	for i := 0; i < len(projects); i++ {
-		if projects[i].Name() == p.Name() {
+		if project.IDOf(projects[i]).Key() == key {
 			return true
 		}
 	}
 	return false
 }
+
+// timestamp returns the time of t, or nil if GitHub did not report it.
+func timestamp(t *github.Timestamp) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	tt := t.Time
+	return &tt
+}
+
+// repoMetadata returns the metadata of a repository as reported by the search
+// response. Attributes that GitHub does not have a typed field for are only set
+// if they are non-empty.
+func repoMetadata(repo *github.Repository) *project.Metadata {
+	m := &project.Metadata{
+		Stars:         repo.StargazersCount,
+		Language:      repo.GetLanguage(),
+		License:       repo.GetLicense().GetKey(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Archived:      repo.GetArchived(),
+		Fork:          repo.GetFork(),
+		Parent:        repo.GetParent().GetFullName(),
+		Topics:        repo.Topics,
+		CreatedAt:     timestamp(repo.CreatedAt),
+		PushedAt:      timestamp(repo.PushedAt),
+		Size:          repoSize(repo),
+	}
+
+	attrs := map[string]string{
+		"html_url":    repo.GetHTMLURL(),
+		"description": repo.GetDescription(),
+	}
+
+	counts := map[string]int{
+		"forks_count":       repo.GetForksCount(),
+		"open_issues_count": repo.GetOpenIssuesCount(),
+		"watchers_count":    repo.GetWatchersCount(),
+	}
+
+	for k, v := range counts {
+		if v != 0 {
+			attrs[k] = strconv.Itoa(v)
+		}
+	}
+
+	for k, v := range attrs {
+		if len(v) == 0 {
+			continue
+		}
+
+		if m.Attributes == nil {
+			m.Attributes = make(map[string]string)
+		}
+		m.Attributes[k] = v
+	}
+
+	return m
+}
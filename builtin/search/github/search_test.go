@@ -1,7 +1,13 @@
 package github
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/github"
+	"gopkg.in/src-d/go-billy.v4"
 
 	"github.com/mccurdyc/neighbor/sdk/project"
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
@@ -73,6 +79,7 @@ type mockProject struct {
 	retrievalFunc  retrieval.Backend
 	sourceLocation string
 	localLocation  string
+	filesystem     billy.Filesystem
 }
 
 func (m *mockProject) Name() string                     { return m.name }
@@ -84,6 +91,11 @@ func (m *mockProject) SetLocalLocation(l string) project.Backend {
 	m.localLocation = l
 	return m
 }
+func (m *mockProject) Filesystem() billy.Filesystem { return m.filesystem }
+func (m *mockProject) SetFilesystem(fs billy.Filesystem) project.Backend {
+	m.filesystem = fs
+	return m
+}
 
 func Test_contains(t *testing.T) {
 	type input struct {
@@ -122,6 +134,36 @@ func Test_contains(t *testing.T) {
 			},
 			want: want{value: false},
 		},
+
+		"same_name_different_host": {
+			input: input{
+				projects: []project.Backend{
+					&mockProject{name: "one/one", sourceLocation: "https://github.com/one/one.git"},
+				},
+				p: &mockProject{name: "one/one", sourceLocation: "https://github.example.com/one/one.git"},
+			},
+			want: want{value: false},
+		},
+
+		"same_project_different_url": {
+			input: input{
+				projects: []project.Backend{
+					&mockProject{name: "one/one", sourceLocation: "https://github.com/one/one.git"},
+				},
+				p: &mockProject{name: "one/one", sourceLocation: "git@github.com:one/one.git"},
+			},
+			want: want{value: true},
+		},
+
+		"different_case": {
+			input: input{
+				projects: []project.Backend{
+					&mockProject{name: "Foo/x", sourceLocation: "https://github.com/Foo/x.git"},
+				},
+				p: &mockProject{name: "foo/x", sourceLocation: "https://github.com/foo/x.git"},
+			},
+			want: want{value: true},
+		},
 	}
 
 	for name, tt := range tests {
@@ -135,3 +177,94 @@ func Test_contains(t *testing.T) {
 		})
 	}
 }
+
+func Test_repoMetadata(t *testing.T) {
+	created := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var tests = map[string]struct {
+		input *github.Repository
+		want  *project.Metadata
+	}{
+		"empty": {
+			input: &github.Repository{},
+			want:  &project.Metadata{},
+		},
+
+		"populated": {
+			input: &github.Repository{
+				StargazersCount: github.Int(42),
+				Language:        github.String("Go"),
+				License:         &github.License{Key: github.String("mit")},
+				DefaultBranch:   github.String("master"),
+				Archived:        github.Bool(true),
+				Fork:            github.Bool(true),
+				Parent:          &github.Repository{FullName: github.String("upstream/repo")},
+				Topics:          []string{"cli"},
+				CreatedAt:       &github.Timestamp{Time: created},
+				Size:            github.Int(2),
+				Description:     github.String("a project"),
+				ForksCount:      github.Int(3),
+			},
+			want: &project.Metadata{
+				Stars:         github.Int(42),
+				Language:      "Go",
+				License:       "mit",
+				DefaultBranch: "master",
+				Archived:      true,
+				Fork:          true,
+				Parent:        "upstream/repo",
+				Topics:        []string{"cli"},
+				CreatedAt:     &created,
+				Size:          2048,
+				Attributes: map[string]string{
+					"description": "a project",
+					"forks_count": "3",
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := repoMetadata(tt.input)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("repoMetadata() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_searchRepositories_parent(t *testing.T) {
+	upstream := newRepo("upstream", "tool", false)
+	fork := newRepo("a", "tool", true)
+
+	forkDetails := newRepo("a", "tool", true)
+	forkDetails.Parent = upstream
+
+	c := Client{
+		SearchService: &mockClient{
+			repositories: &github.RepositoriesSearchResult{
+				Repositories: []github.Repository{*upstream, *fork},
+			},
+		},
+		RepositoryService: &mockRepositories{
+			repos: map[string]*github.Repository{"a/tool": forkDetails},
+		},
+	}
+
+	got, _, err := searchRepositories(context.TODO(), c, "tool", 2, &github.SearchOptions{})
+	if err != nil {
+		t.Fatalf("searchRepositories() unexpected error: %+v", err)
+	}
+
+	var parents []string
+	for _, p := range got {
+		parents = append(parents, project.MetadataOf(p).Parent)
+	}
+
+	if diff := cmp.Diff([]string{"", "upstream/tool"}, parents); diff != "" {
+		t.Errorf("searchRepositories() mismatched parents (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,78 @@
+package goproxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"golang.org/x/mod/semver"
+
+	"github.com/mccurdyc/neighbor/builtin/internal/goproxy"
+	"github.com/mccurdyc/neighbor/builtin/project/generic"
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// Factory is the factory function to be used to create a Go module proxy search
+// backend.
+//
+// The optional "proxy" config value is the url of the proxy (defaults to
+// https://proxy.golang.org). file:// urls are supported for local proxies.
+func Factory(ctx context.Context, conf *search.BackendConfig) (search.Backend, error) {
+	if conf.SearchMethod != search.Version {
+		return nil, fmt.Errorf("only the Version search method is supported")
+	}
+
+	c, err := goproxy.NewClient(conf.Config["proxy"], conf.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		client: c,
+	}, nil
+}
+
+// Backend is a Go module proxy search backend.
+type Backend struct {
+	client *goproxy.Client
+}
+
+// Search lists the versions of the module specified by query, newest first. Each
+// version is returned as a separate project whose source location is `module@version`.
+func (b *Backend) Search(ctx context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
+	versions, err := b.client.List(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := versions[:0]
+	for _, v := range versions {
+		if semver.IsValid(v) {
+			valid = append(valid, v)
+		}
+	}
+
+	sort.Slice(valid, func(i, j int) bool {
+		return semver.Compare(valid[i], valid[j]) > 0
+	})
+
+	res := make([]project.Backend, 0, numDesiredResults)
+	for _, v := range valid {
+		p, err := generic.Factory(ctx, &project.BackendConfig{
+			Name:           query,
+			Version:        v,
+			SourceLocation: fmt.Sprintf("%s@%s", query, v),
+		})
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, p)
+		if len(res) >= numDesiredResults {
+			return res, nil
+		}
+	}
+
+	return res, search.ErrFewerResultsThanDesired
+}
@@ -0,0 +1,164 @@
+package goproxy
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+func Test_Factory(t *testing.T) {
+	type input struct {
+		conf *search.BackendConfig
+	}
+
+	type want struct {
+		err error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"unsupported_search_method": {
+			input: input{
+				conf: &search.BackendConfig{
+					SearchMethod: search.Project,
+				},
+			},
+			want: want{
+				err: fmt.Errorf("only the Version search method is supported"),
+			},
+		},
+
+		"unsupported_proxy_scheme": {
+			input: input{
+				conf: &search.BackendConfig{
+					SearchMethod: search.Version,
+					Config:       map[string]string{"proxy": "ftp://example.com"},
+				},
+			},
+			want: want{
+				err: fmt.Errorf("unsupported proxy scheme 'ftp'"),
+			},
+		},
+
+		"default_proxy": {
+			input: input{
+				conf: &search.BackendConfig{
+					SearchMethod: search.Version,
+				},
+			},
+			want: want{
+				err: nil,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, gotErr := Factory(context.TODO(), tt.input.conf)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Factory() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
+
+func Test_Search(t *testing.T) {
+	root, err := ioutil.TempDir("", "neighbor-goproxy")
+	if err != nil {
+		t.Fatalf("failed to create proxy directory: %+v", err)
+	}
+	defer os.RemoveAll(root)
+
+	// module paths with upper case letters are escaped by the proxy protocol.
+	vdir := filepath.Join(root, "example.com", "!my!mod", "@v")
+	if err := os.MkdirAll(vdir, 0755); err != nil {
+		t.Fatalf("failed to create proxy directory: %+v", err)
+	}
+
+	list := "v1.0.0\nv1.10.0\nnot-a-version\nv1.2.0\n"
+	if err := ioutil.WriteFile(filepath.Join(vdir, "list"), []byte(list), 0644); err != nil {
+		t.Fatalf("failed to write version list: %+v", err)
+	}
+
+	type input struct {
+		query             string
+		numDesiredResults int
+	}
+
+	type want struct {
+		sources []string
+		err     error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"newest_first": {
+			input: input{
+				query:             "example.com/MyMod",
+				numDesiredResults: 2,
+			},
+			want: want{
+				sources: []string{"example.com/MyMod@v1.10.0", "example.com/MyMod@v1.2.0"},
+				err:     nil,
+			},
+		},
+
+		"less_than_desired": {
+			input: input{
+				query:             "example.com/MyMod",
+				numDesiredResults: 5,
+			},
+			want: want{
+				sources: []string{"example.com/MyMod@v1.10.0", "example.com/MyMod@v1.2.0", "example.com/MyMod@v1.0.0"},
+				err:     search.ErrFewerResultsThanDesired,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			b, err := Factory(context.TODO(), &search.BackendConfig{
+				SearchMethod: search.Version,
+				Config:       map[string]string{"proxy": "file://" + root},
+			})
+			if err != nil {
+				t.Fatalf("Factory() unexpected error: %+v", err)
+			}
+
+			got, gotErr := b.Search(context.TODO(), tt.input.query, tt.input.numDesiredResults)
+			if gotErr != tt.want.err {
+				t.Errorf("Search() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			var sources []string
+			for _, p := range got {
+				sources = append(sources, p.SourceLocation())
+			}
+
+			if diff := cmp.Diff(tt.want.sources, sources); diff != "" {
+				t.Errorf("Search() mismatched sources (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
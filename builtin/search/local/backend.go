@@ -0,0 +1,120 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	git "gopkg.in/src-d/go-git.v4"
+
+	"github.com/mccurdyc/neighbor/builtin/project/generic"
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// Factory is the factory function to be used to create a search backend for
+// projects in local directories, e.g., a local corpus of Git repositories.
+//
+// The optional "root" config value is the directory that relative queries are
+// relative to (defaults to the working directory).
+func Factory(ctx context.Context, conf *search.BackendConfig) (search.Backend, error) {
+	if conf.SearchMethod != search.Project {
+		return nil, fmt.Errorf("only the Project search method is supported")
+	}
+
+	root := conf.Config["root"]
+	if len(root) == 0 {
+		root = "."
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %+v", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root '%s' must be a directory", root)
+	}
+
+	return &Backend{
+		root: root,
+	}, nil
+}
+
+// Backend is a local directory search backend.
+type Backend struct {
+	root string
+}
+
+// Search returns the directories that match query, which is a filepath.Match
+// pattern (e.g., `repos/*`) relative to the root directory, sorted by path. The
+// name of each project is its path relative to the root directory and its source
+// location is its absolute path. The version of projects that are Git
+// repositories is the commit SHA of their HEAD.
+func (b *Backend) Search(ctx context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
+	if len(query) == 0 {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	pattern := query
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(b.root, pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query '%s': %+v", query, err)
+	}
+	sort.Strings(matches)
+
+	res := make([]project.Backend, 0, numDesiredResults)
+	for _, m := range matches {
+		if info, err := os.Stat(m); err != nil || !info.IsDir() {
+			continue
+		}
+
+		name, err := filepath.Rel(b.root, m)
+		if err != nil {
+			name = m
+		}
+
+		p, err := generic.Factory(ctx, &project.BackendConfig{
+			Name:           filepath.ToSlash(name),
+			Version:        headVersion(m),
+			SourceLocation: m,
+		})
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, p)
+		if len(res) >= numDesiredResults {
+			return res, nil
+		}
+	}
+
+	return res, search.ErrFewerResultsThanDesired
+}
+
+// headVersion returns the commit SHA checked out in dir or an empty string if dir
+// is not a Git repository.
+func headVersion(dir string) string {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		return ""
+	}
+
+	ref, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+
+	return ref.Hash().String()
+}
@@ -0,0 +1,175 @@
+package local
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// newRepo initializes a Git repository with a single commit at dir and returns
+// the SHA of the commit.
+func newRepo(t *testing.T, dir string) string {
+	t.Helper()
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to initialize repository: %+v", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %+v", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %+v", err)
+	}
+
+	if _, err := wt.Add("main.go"); err != nil {
+		t.Fatalf("failed to add file: %+v", err)
+	}
+
+	sig := &object.Signature{Name: "neighbor", Email: "neighbor@example.com", When: time.Unix(0, 0)}
+	h, err := wt.Commit("initial commit", &git.CommitOptions{Author: sig, Committer: sig})
+	if err != nil {
+		t.Fatalf("failed to commit: %+v", err)
+	}
+
+	return h.String()
+}
+
+func Test_Search(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "neighbor-local-search")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, d := range []string{"repos/b", "repos/a", "other/c"} {
+		if err := os.MkdirAll(filepath.Join(tmp, d), 0755); err != nil {
+			t.Fatalf("failed to create directory: %+v", err)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(tmp, "repos", "file"), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %+v", err)
+	}
+
+	sha := newRepo(t, filepath.Join(tmp, "repos", "a"))
+
+	type input struct {
+		conf  *search.BackendConfig
+		query string
+		num   int
+	}
+
+	type want struct {
+		projects   []string
+		factoryErr error
+		err        error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"pattern": {
+			input: input{
+				conf:  &search.BackendConfig{Config: map[string]string{"root": tmp}},
+				query: "repos/*",
+				num:   2,
+			},
+			want: want{
+				projects: []string{
+					fmt.Sprintf("repos/a %s %s", filepath.Join(tmp, "repos", "a"), sha),
+					fmt.Sprintf("repos/b %s ", filepath.Join(tmp, "repos", "b")),
+				},
+			},
+		},
+
+		"fewer_than_desired": {
+			input: input{
+				conf:  &search.BackendConfig{Config: map[string]string{"root": tmp}},
+				query: "other/*",
+				num:   2,
+			},
+			want: want{
+				projects: []string{fmt.Sprintf("other/c %s ", filepath.Join(tmp, "other", "c"))},
+				err:      search.ErrFewerResultsThanDesired,
+			},
+		},
+
+		"empty_query": {
+			input: input{
+				conf: &search.BackendConfig{Config: map[string]string{"root": tmp}},
+			},
+			want: want{
+				err: fmt.Errorf("query cannot be empty"),
+			},
+		},
+
+		"missing_root": {
+			input: input{
+				conf: &search.BackendConfig{Config: map[string]string{"root": filepath.Join(tmp, "missing")}},
+			},
+			want: want{
+				factoryErr: fmt.Errorf("invalid root: stat %s: no such file or directory", filepath.Join(tmp, "missing")),
+			},
+		},
+
+		"unsupported_search_method": {
+			input: input{
+				conf: &search.BackendConfig{SearchMethod: search.Code},
+			},
+			want: want{
+				factoryErr: fmt.Errorf("only the Project search method is supported"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			b, gotErr := Factory(context.TODO(), tt.input.conf)
+			if ok := errorCmp(gotErr, tt.want.factoryErr); !ok {
+				t.Fatalf("Factory() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.factoryErr)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			projects, gotErr := b.Search(context.TODO(), tt.input.query, tt.input.num)
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Search() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			var got []string
+			for _, p := range projects {
+				got = append(got, fmt.Sprintf("%s %s %s", p.Name(), p.SourceLocation(), p.Version()))
+			}
+
+			if diff := cmp.Diff(tt.want.projects, got); diff != "" {
+				t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/golang/glog"
+
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+// command is a subcommand of neighbor, which runs a single phase of an experiment.
+type command struct {
+	name     string
+	synopsis string
+	run      func(args []string) error
+}
+
+// commands are the subcommands of neighbor, in the order that they are run.
+var commands = []command{
+	{name: "search", synopsis: "Search for projects and write a manifest of them.", run: searchCmd},
+	{name: "retrieve", synopsis: "Retrieve the projects of a manifest into the projects directory.", run: retrieveCmd},
+	{name: "run", synopsis: "Run a command on each project retrieved into the projects directory.", run: runCmd},
+	{name: "report", synopsis: "Summarize the retrieval and run results of the projects directory.", run: reportCmd},
+	{name: "config", synopsis: "Print the effective config merged from defaults, the config file, the environment and flags.", run: configCmd},
+}
+
+// lookupCommand returns the subcommand named name.
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+
+	return command{}, false
+}
+
+func searchCmd(args []string) error {
+	var c Contents
+
+	fs := newFlagSet("search", "neighbor search (--file=<file> [--experiment=<name>] | --query=<string> | --corpus=<file>) [--output=<file>] [--search_type=<repository|code|version>] [--num_projects=<int>] [--collapse_forks] [--dedupe_content] [--filter=<expression>] [--sample=<int> [--sample_pool=<int>] [--seed=<int>] [--sample_strategy=<uniform|stratified> [--sample_stratum=<field>]]] [auth flags] [--cache_dir=<dir> [--cache_ttl=<duration>]]")
+	fp := fs.String("file", "", "Absolute filepath to the config file (.json, .yaml, .yml or .toml).")
+	experiment := experimentFlag(fs)
+	output := fs.String("output", "-", "Filepath to write the manifest of the projects found to (- is stdout).")
+	authFlags(fs, &c)
+	corpusFlags(fs, &c)
+	searchFlags(fs, &c)
+	fs.Parse(args)
+
+	if _, err := loadContents(fs, *fp, *experiment, &c); err != nil {
+		return fmt.Errorf("failed to load config: %+v", err)
+	}
+
+	if len(c.Query) == 0 && len(c.Queries) == 0 && len(c.Corpus) == 0 {
+		fs.Usage()
+		return fmt.Errorf("one of query, queries or corpus is required")
+	}
+
+	ctx := signalContext()
+
+	m, err := searchProjects(ctx, &c)
+	if err != nil {
+		return err
+	}
+
+	if err := writeManifest(*output, m); err != nil {
+		return fmt.Errorf("failed to write manifest: %+v", err)
+	}
+	glog.Infof("found %d project(s)", len(m.Projects))
+
+	return nil
+}
+
+func retrieveCmd(args []string) error {
+	var c Contents
+
+	fs := newFlagSet("retrieve", "neighbor retrieve --manifest=<file> [--file=<file> [--experiment=<name>]] [--projects_directory=<string>] [--corpus=<file> [--corpus_manifest=<file>]] [--submodules [--submodule_depth=<int>]] [--lfs] [--max_retrieval_attempts=<int>] [--max_repo_size=<bytes>] [--max_files=<int>] [--max_bytes=<bytes>] [--gosumdb=<database|off> | --go_sum_file=<file>] [--local_mode=<auto|reflink|hardlink|copy|clone>] [--export=<file>] [--resume] [auth flags]")
+	fp := fs.String("file", "", "Absolute filepath to the config file (.json, .yaml, .yml or .toml).")
+	experiment := experimentFlag(fs)
+	manifest := fs.String("manifest", "", "Filepath of the manifest written by the search command (- is stdin).")
+	resume := resumeFlag(fs)
+	authFlags(fs, &c)
+	corpusFlags(fs, &c)
+	retrieveFlags(fs, &c)
+	projectsDirFlag(fs, &c)
+	fs.Parse(args)
+
+	if _, err := loadContents(fs, *fp, *experiment, &c); err != nil {
+		return fmt.Errorf("failed to load config: %+v", err)
+	}
+
+	if len(*manifest) == 0 {
+		fs.Usage()
+		return fmt.Errorf("manifest is required")
+	}
+
+	m, err := readManifest(*manifest)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %+v", err)
+	}
+
+	if err := os.MkdirAll(c.ProjectsDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create project directory: %+v", err)
+	}
+
+	root, err := filepath.Abs(c.ProjectsDir)
+	if err != nil {
+		return err
+	}
+
+	ctx := signalContext()
+
+	retriever, err := newRetrievers(ctx, &c, m)
+	if err != nil {
+		return err
+	}
+
+	if *resume {
+		prev, err := readState(root)
+		if err != nil {
+			return err
+		}
+		mergeState(m, prev)
+	}
+
+	j, err := openJournal(root)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	limits := retrieval.Limits{MaxSize: c.MaxRepoSize}
+	if err := retrieveProjects(ctx, j, retriever, limits, root, m, *resume); err != nil {
+		return err
+	}
+
+	reportSkipped(os.Stderr, m)
+
+	if len(c.Export) != 0 {
+		return exportProjects(c.Export, root, m)
+	}
+
+	return nil
+}
+
+func runCmd(args []string) error {
+	c := defaultContents()
+
+	fs := newFlagSet("run", "neighbor run (--experiments=<file> [--experiment=<name>] | (--file=<file> [--experiment=<name>] | --command=<string>) [--projects_directory=<string>]) [--resume]")
+	fp := fs.String("file", "", "Absolute filepath to the config file (.json, .yaml, .yml or .toml).")
+	experimentsFile := fs.String("experiments", "", "Filepath of a config file whose experiments are each searched, retrieved and run, in order, instead of running the command on the projects retrieved into the projects directory.")
+	experiment := experimentFlag(fs)
+	resume := resumeFlag(fs)
+	commandFlag(fs, &c)
+	projectsDirFlag(fs, &c)
+	fs.Parse(args)
+
+	// experiments are run from start to finish, rather than on the projects that
+	// were retrieved into the projects directory.
+	if len(*experimentsFile) != 0 {
+		if len(*fp) != 0 {
+			fs.Usage()
+			return fmt.Errorf("cannot use both file and experiments")
+		}
+
+		experiments := []string{*experiment}
+		if len(*experiment) == 0 {
+			var err error
+			experiments, err = configExperiments(*experimentsFile)
+			if err != nil {
+				return err
+			}
+
+			if len(experiments) == 0 {
+				return fmt.Errorf("config file '%s' has no experiments", *experimentsFile)
+			}
+		}
+
+		return runExperiments(fs, *experimentsFile, experiments, c, *resume)
+	}
+
+	if _, err := loadContents(fs, *fp, *experiment, &c); err != nil {
+		return fmt.Errorf("failed to load config: %+v", err)
+	}
+
+	if len(c.Command) == 0 {
+		fs.Usage()
+		return fmt.Errorf("command is required")
+	}
+
+	root, err := filepath.Abs(c.ProjectsDir)
+	if err != nil {
+		return err
+	}
+
+	m, err := readState(root)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("no projects were retrieved into '%s'", c.ProjectsDir)
+	}
+
+	ctx := signalContext()
+
+	cmd, err := newRunner(ctx, &c)
+	if err != nil {
+		return err
+	}
+
+	j, err := openJournal(root)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	return runProjects(ctx, j, cmd, root, m, *resume)
+}
+
+// runExperiments runs the experiments of the config file at fp named names, in
+// order. Every experiment is loaded and validated before any of them is run. If
+// resume, each experiment resumes the run journaled in its projects directory.
+func runExperiments(fs *flag.FlagSet, fp string, names []string, defaults Contents, resume bool) error {
+	experiments := make([]Contents, len(names))
+	dirs := make(map[string]string, len(names))
+
+	for i, name := range names {
+		c := defaults
+		if _, err := loadContents(fs, fp, name, &c); err != nil {
+			return fmt.Errorf("failed to load experiment '%s': %+v", name, err)
+		}
+
+		if len(c.Query) == 0 && len(c.Queries) == 0 && len(c.Corpus) == 0 {
+			return fmt.Errorf("experiment '%s': one of query, queries or corpus is required", name)
+		}
+
+		// experiments that share a projects directory would overwrite each other's
+		// projects and state.
+		dir, err := filepath.Abs(c.ProjectsDir)
+		if err != nil {
+			return err
+		}
+
+		if other, ok := dirs[dir]; ok {
+			return fmt.Errorf("experiments '%s' and '%s' have the same projects_directory '%s'", other, name, c.ProjectsDir)
+		}
+		dirs[dir] = name
+
+		experiments[i] = c
+	}
+
+	ctx := signalContext()
+
+	for i, name := range names {
+		glog.Infof("running experiment '%s'", name)
+
+		if err := runExperiment(ctx, &experiments[i], resume); err != nil {
+			return fmt.Errorf("experiment '%s': %+v", name, err)
+		}
+	}
+
+	return nil
+}
+
+func reportCmd(args []string) error {
+	var c Contents
+
+	fs := newFlagSet("report", "neighbor report [--projects_directory=<string>] [--format=<text|json>]")
+	format := fs.String("format", "text", "The format of the report (text or json).")
+	projectsDirFlag(fs, &c)
+	fs.Parse(args)
+
+	if _, err := loadContents(fs, "", "", &c); err != nil {
+		return fmt.Errorf("failed to load config: %+v", err)
+	}
+
+	m, err := readState(c.ProjectsDir)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("no projects were searched for in '%s'", c.ProjectsDir)
+	}
+
+	s := summarize(m)
+
+	switch *format {
+	case "text":
+		writeSummary(os.Stdout, s)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	default:
+		return fmt.Errorf("unsupported format '%s'", *format)
+	}
+
+	return nil
+}
+
+func configCmd(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: neighbor config print [flags]")
+	}
+
+	var c Contents
+
+	fs := newFlagSet("config print", "neighbor config print [--file=<file> [--experiment=<name>]] [--sources] [flags of any command]")
+	fp := fs.String("file", "", "Absolute filepath to the config file (.json, .yaml, .yml or .toml).")
+	experiment := experimentFlag(fs)
+	showSources := fs.Bool("sources", false, "Print where the value of each field came from (default, file, env or flag) instead of the config.")
+	authFlags(fs, &c)
+	corpusFlags(fs, &c)
+	searchFlags(fs, &c)
+	retrieveFlags(fs, &c)
+	projectsDirFlag(fs, &c)
+	commandFlag(fs, &c)
+	legacyFlags(fs, &c)
+	fs.Parse(args[1:])
+
+	sources, err := loadContents(fs, *fp, *experiment, &c)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %+v", err)
+	}
+
+	// the effective config is likely to be shared, so it must not leak the token.
+	if len(c.AuthToken) != 0 {
+		c.AuthToken = "REDACTED"
+	}
+
+	if *showSources {
+		return writeSources(os.Stdout, &c, sources)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// writeSources writes the value of each field of c and where it came from.
+func writeSources(w io.Writer, c *Contents, sources Sources) error {
+	fields := contentsFields(c)
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v, err := json.Marshal(fields[name].Interface())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%s=%s (%s)\n", name, v, sources[name])
+	}
+
+	return nil
+}
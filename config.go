@@ -2,62 +2,372 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/golang/glog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/mccurdyc/neighbor/sdk/filter"
+	"github.com/mccurdyc/neighbor/sdk/search"
 )
 
 // Contents contains the contents of the parsed config file.
 type Contents struct {
-	AuthToken  string `json:"auth_token"`
-	SearchType string `json:"search_type"`
-	Query      string `json:"query"`
+	AuthToken            string `json:"auth_token"`
+	AuthTokenFile        string `json:"auth_token_file"`
+	GitHubAppID          string `json:"github_app_id"`
+	GitHubInstallationID string `json:"github_installation_id"`
+	GitHubPrivateKeyFile string `json:"github_private_key_file"`
+	GitHubBaseURL        string `json:"github_base_url"`
+	GitHubUploadURL      string `json:"github_upload_url"`
+	CacheDir             string `json:"cache_dir"`
+	CacheTTL             string `json:"cache_ttl"`
+	SearchType           string `json:"search_type"`
+	Query                string `json:"query"`
+	Filter               string `json:"filter"`
+	CollapseForks        bool   `json:"collapse_forks"`
+	DedupeContent        bool   `json:"dedupe_content"`
+
+	// Queries are combined into a composite search that is used instead of Query.
+	Queries []QueryContents `json:"queries"`
+
+	Sample         int    `json:"sample"`
+	SamplePool     int    `json:"sample_pool"`
+	Seed           int64  `json:"seed"`
+	SampleStrategy string `json:"sample_strategy"`
+	SampleStratum  string `json:"sample_stratum"`
 
 	Command       string `json:"command"`
 	NumProjects   int    `json:"num_projects"`
 	ProjectsDir   string `json:"projects_directory"`
 	PlainRetrieve bool   `json:"plain_retrieve"`
 	Clean         bool   `json:"clean"`
+
+	Submodules     bool `json:"submodules"`
+	SubmoduleDepth int  `json:"submodule_depth"`
+	LFS            bool `json:"lfs"`
+
+	MaxRetrievalAttempts int   `json:"max_retrieval_attempts"`
+	MaxRepoSize          int64 `json:"max_repo_size"`
+	MaxFiles             int64 `json:"max_files"`
+	MaxBytes             int64 `json:"max_bytes"`
+
+	// GoSumDB is the checksum database that modules retrieved from a Go module
+	// proxy are verified against, in the format of GOSUMDB (or off).
+	GoSumDB   string `json:"gosumdb"`
+	GoSumFile string `json:"go_sum_file"`
+	// LocalMode is how local projects are placed in the projects directory.
+	LocalMode string `json:"local_mode"`
+
+	Export         string `json:"export"`
+	Corpus         string `json:"corpus"`
+	CorpusManifest string `json:"corpus_manifest"`
 }
 
-// Config specifies information about the config file used for performing the experiment.
-type Config struct {
-	FilePath string
-	Contents *Contents
+// QueryContents contains a single query of a composite search.
+type QueryContents struct {
+	Query string `json:"query"`
+	// Backend is the search backend of the query (github, corpus, goproxy or local).
+	Backend    string `json:"backend"`
+	SearchType string `json:"search_type"`
+	// Operation is how the results of the query are combined with the results of
+	// the preceding queries (union, intersection or difference).
+	Operation   string            `json:"operation"`
+	NumProjects int               `json:"num_projects"`
+	Config      map[string]string `json:"config"`
 }
 
-// NewCfg is a constructor that returns a pointer to a Config object.
-func NewCfg(fp string) *Config {
-	return &Config{
-		FilePath: fp,
+// parse decodes the config file in r, in the format of the extension ext, into c.
+func parse(r io.Reader, ext string, c *Contents) error {
+	n, err := decodeFile(r, ext)
+	if err != nil {
+		return err
 	}
+
+	// the experiments of the config file are loaded by name (see loadContents).
+	fields, experiments, err := splitExperiments(n)
+	if err != nil {
+		return err
+	}
+
+	if err := unselectedExperimentsError(experiments); err != nil {
+		return err
+	}
+
+	return decodeNode(&yaml.Node{Kind: yaml.MappingNode, Content: flatten(fields)}, reflect.ValueOf(c).Elem(), "")
 }
 
-// Parse opens a file and calls a private `parse` method.
-func (cfg *Config) Parse() {
-	f, err := os.Open(cfg.FilePath)
+// readConfigFile decodes the config file at fp and returns its top-level mapping.
+func readConfigFile(fp string) (*yaml.Node, error) {
+	f, err := os.Open(fp)
 	if err != nil {
-		glog.Errorf("error opening config file %+v", err)
-		return
+		return nil, fmt.Errorf("error opening config file: %+v", err)
 	}
 	defer f.Close()
 
-	c := &Contents{}
-	if err := parse(f, c); err != nil {
-		glog.Errorf("error parsing config file %+v", err)
-		return
+	n, err := decodeFile(f, filepath.Ext(fp))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file '%s': %w", fp, err)
 	}
 
-	cfg.Contents = c
+	return n, nil
 }
 
-func parse(f io.Reader, d interface{}) error {
-	b, _ := ioutil.ReadAll(f)
+// configExperiments returns the names of the experiments of the config file at
+// fp, in the order that they are in the file.
+func configExperiments(fp string) ([]string, error) {
+	n, err := readConfigFile(fp)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := json.Unmarshal(b, d); err != nil {
-		return err
+	_, experiments, err := splitExperiments(n)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file '%s': %w", fp, err)
+	}
+
+	return experimentNames(experiments), nil
+}
+
+// validate checks that the search type and the search types, backends and
+// operations of the queries of c are supported, and that c does not have
+// conflicting options.
+func (c *Contents) validate() error {
+	if len(c.SearchType) != 0 {
+		if _, err := searchMethod(c.SearchType); err != nil {
+			return &ConfigError{Field: "search_type", Msg: err.Error()}
+		}
+	}
+
+	for i, q := range c.Queries {
+		field := fmt.Sprintf("queries[%d]", i)
+
+		if len(q.SearchType) != 0 {
+			if _, err := searchMethod(q.SearchType); err != nil {
+				return &ConfigError{Field: field + ".search_type", Msg: err.Error()}
+			}
+		}
+
+		if _, ok := searchFactories[q.Backend]; len(q.Backend) != 0 && !ok {
+			return &ConfigError{Field: field + ".backend", Msg: fmt.Sprintf("unsupported search backend '%s'", q.Backend)}
+		}
+
+		switch q.Operation {
+		case "", search.Union, search.Intersection, search.Difference:
+		default:
+			return &ConfigError{Field: field + ".operation", Msg: fmt.Sprintf("unsupported operation '%s'", q.Operation)}
+		}
+	}
+
+	if len(c.Filter) != 0 {
+		if _, err := filter.Parse(c.Filter); err != nil {
+			return &ConfigError{Field: "filter", Msg: err.Error()}
+		}
+	}
+
+	if len(c.CacheTTL) != 0 {
+		if _, err := time.ParseDuration(c.CacheTTL); err != nil {
+			return &ConfigError{Field: "cache_ttl", Msg: err.Error()}
+		}
+	}
+
+	if c.Sample != 0 {
+		if _, err := sampleOptions(c); err != nil {
+			return &ConfigError{Field: "sample", Msg: err.Error()}
+		}
+	}
+
+	// options that are silently ignored in favor of others.
+	conflicts := []struct {
+		a, b string
+		set  bool
+	}{
+		{"plain_retrieve", "clean", c.PlainRetrieve && c.Clean},
+		{"auth_token", "github_app_id", len(c.AuthToken) != 0 && len(c.GitHubAppID) != 0},
+		{"queries", "query", len(c.Queries) != 0 && len(c.Query) != 0},
+		{"queries", "corpus", len(c.Queries) != 0 && len(c.Corpus) != 0},
+	}
+
+	for _, conflict := range conflicts {
+		if conflict.set {
+			return &ConfigError{Field: conflict.a, Msg: fmt.Sprintf("cannot be combined with %s", conflict.b)}
+		}
+	}
+
+	return nil
+}
+
+// Source is where the value of a config field came from.
+type Source string
+
+const (
+	// SourceDefault is the default value of the flag of a field.
+	SourceDefault Source = "default"
+	// SourceFile is the config file.
+	SourceFile Source = "file"
+	// SourceEnv is a NEIGHBOR_* environment variable.
+	SourceEnv Source = "env"
+	// SourceFlag is a flag that was explicitly set on the command line.
+	SourceFlag Source = "flag"
+)
+
+// envPrefix prefixes the name of the environment variable of each field, e.g.,
+// NEIGHBOR_NUM_PROJECTS for num_projects.
+const envPrefix = "NEIGHBOR_"
+
+// lookupEnv is a variable so that tests can replace the environment.
+var lookupEnv = os.LookupEnv
+
+// Sources maps the name of each field of Contents to where its value came from.
+type Sources map[string]Source
+
+// loadContents layers the config file at fp, if fp is not empty, and then the
+// environment over the defaults of c, i.e., defaults < config file < environment
+// < flags. If experiment is not empty, the fields of the experiment of the config
+// file with that name are layered over its other fields. Flags take precedence,
+// so fields of flags that were explicitly set on fs are left untouched. Fields
+// that are missing from the config file keep their value, rather than becoming
+// the zero value. The merged contents are validated, and the errors of fields
+// from the config file have their position.
+func loadContents(fs *flag.FlagSet, fp string, experiment string, c *Contents) (Sources, error) {
+	fields := contentsFields(c)
+
+	sources := make(Sources, len(fields))
+	for name := range fields {
+		sources[name] = SourceDefault
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := fields[f.Name]; ok {
+			sources[f.Name] = SourceFlag
+		}
+	})
+
+	// the positions of the fields of the config file, for validation errors.
+	positions := make(map[string]*yaml.Node)
+
+	if len(fp) != 0 {
+		n, err := readConfigFile(fp)
+		if err != nil {
+			return nil, err
+		}
+
+		shared, experiments, err := splitExperiments(n)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing config file '%s': %w", fp, err)
+		}
+
+		// the fields of an experiment override the fields that it shares with the
+		// other experiments.
+		layers := [][]pair{shared}
+		prefixes := []string{""}
+
+		if len(experiment) != 0 {
+			e, err := findExperiment(experiments, experiment)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing config file '%s': %w", fp, err)
+			}
+
+			layers = append(layers, pairs(e))
+			prefixes = append(prefixes, joinField(experimentsField, experiment))
+		} else if err := unselectedExperimentsError(experiments); err != nil {
+			return nil, fmt.Errorf("error parsing config file '%s': %w", fp, err)
+		}
+
+		for i, layer := range layers {
+			for _, p := range layer {
+				name := p.key.Value
+
+				f, ok := fields[name]
+				if !ok {
+					return nil, fmt.Errorf("error parsing config file '%s': %w", fp, nodeError(p.key, "", "unknown field '%s'", joinField(prefixes[i], name)))
+				}
+
+				if sources[name] == SourceFlag {
+					continue
+				}
+
+				if err := decodeNode(p.value, f, joinField(prefixes[i], name)); err != nil {
+					return nil, fmt.Errorf("error parsing config file '%s': %w", fp, err)
+				}
+				sources[name] = SourceFile
+				positions[name] = p.key
+			}
+		}
+	}
+
+	for name, f := range fields {
+		env := envPrefix + strings.ToUpper(name)
+
+		s, ok := lookupEnv(env)
+		if !ok || sources[name] == SourceFlag {
+			continue
+		}
+
+		if err := setString(f, s); err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %+v", env, err)
+		}
+		sources[name] = SourceEnv
+	}
+
+	if err := c.validate(); err != nil {
+		var cfgErr *ConfigError
+		if errors.As(err, &cfgErr) {
+			// only the top-level field of the error has a position.
+			name := cfgErr.Field
+			if i := strings.IndexAny(name, ".["); i >= 0 {
+				name = name[:i]
+			}
+
+			switch sources[name] {
+			case SourceFile:
+				cfgErr.Line, cfgErr.Column = positions[name].Line, positions[name].Column
+				return nil, fmt.Errorf("invalid config file '%s': %w", fp, cfgErr)
+			case SourceEnv:
+				return nil, fmt.Errorf("invalid %s%s: %w", envPrefix, strings.ToUpper(name), cfgErr)
+			}
+		}
+
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return sources, nil
+}
+
+// contentsFields returns the fields of c by the name of their JSON key, which is
+// also the name of their flag.
+func contentsFields(c *Contents) map[string]reflect.Value {
+	return structFields(reflect.ValueOf(c).Elem())
+}
+
+// setString parses s as the type of f and sets f to it. Fields that are not a
+// string, bool or integer, e.g., queries, are parsed as JSON.
+func setString(f reflect.Value, s string) error {
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.SetInt(i)
+	default:
+		return json.Unmarshal([]byte(s), f.Addr().Interface())
 	}
 
 	return nil
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -11,6 +16,7 @@ import (
 func Test_parse(t *testing.T) {
 	type input struct {
 		reader  io.Reader
+		ext     string
 		content *Contents
 	}
 
@@ -27,36 +33,245 @@ func Test_parse(t *testing.T) {
 			input: input{
 				reader: strings.NewReader(`{
 															"auth_token": "123abc",
+															"auth_token_file": "/secrets/token",
+															"github_app_id": "7",
+															"github_installation_id": "42",
+															"github_private_key_file": "/keys/app.pem",
+															"github_base_url": "https://github.example.com",
+															"github_upload_url": "https://uploads.example.com",
+															"cache_dir": "/tmp/neighbor-cache",
+															"cache_ttl": "1h",
 															"search_type": "type",
 															"query": "query",
+															"filter": "!fork",
+															"collapse_forks": true,
+															"dedupe_content": true,
+															"sample": 5,
+															"sample_pool": 100,
+															"seed": 42,
+															"sample_strategy": "stratified",
+															"sample_stratum": "stars",
+															"queries": [
+																{"query": "language:go", "search_type": "code"},
+																{"query": "*", "backend": "corpus", "operation": "difference", "num_projects": 50, "config": {"archive": "old.tar.zst"}}
+															],
 															"command": "hello",
 															"plain_retrieve": true,
 															"clean": false,
 															"projects_directory": "/hello/there",
-															"num_projects": 11
+															"num_projects": 11,
+															"submodules": true,
+															"submodule_depth": 2,
+															"lfs": true,
+															"max_retrieval_attempts": 5,
+															"max_repo_size": 1048576,
+															"max_files": 100,
+															"max_bytes": 2097152,
+															"export": "corpus.tar.zst",
+															"corpus": "published.tar.zst",
+															"corpus_manifest": "manifest.json"
 														}`),
+				ext:     ".json",
 				content: &Contents{},
 			},
 			want: want{
 				content: Contents{
-					AuthToken:     "123abc",
-					SearchType:    "type",
-					Query:         "query",
+					AuthToken:            "123abc",
+					AuthTokenFile:        "/secrets/token",
+					GitHubAppID:          "7",
+					GitHubInstallationID: "42",
+					GitHubPrivateKeyFile: "/keys/app.pem",
+					GitHubBaseURL:        "https://github.example.com",
+					GitHubUploadURL:      "https://uploads.example.com",
+					CacheDir:             "/tmp/neighbor-cache",
+					CacheTTL:             "1h",
+					SearchType:           "type",
+					Query:                "query",
+					Filter:               "!fork",
+					CollapseForks:        true,
+					DedupeContent:        true,
+					Sample:               5,
+					SamplePool:           100,
+					Seed:                 42,
+					SampleStrategy:       "stratified",
+					SampleStratum:        "stars",
+					Queries: []QueryContents{
+						{Query: "language:go", SearchType: "code"},
+						{Query: "*", Backend: "corpus", Operation: "difference", NumProjects: 50, Config: map[string]string{"archive": "old.tar.zst"}},
+					},
+
 					Command:       "hello",
 					PlainRetrieve: true,
 					Clean:         false,
 					ProjectsDir:   "/hello/there",
 					NumProjects:   11,
+
+					Submodules:     true,
+					SubmoduleDepth: 2,
+					LFS:            true,
+
+					MaxRetrievalAttempts: 5,
+					MaxRepoSize:          1048576,
+					MaxFiles:             100,
+					MaxBytes:             2097152,
+
+					Export:         "corpus.tar.zst",
+					Corpus:         "published.tar.zst",
+					CorpusManifest: "manifest.json",
 				},
 				err: nil,
 			},
 		},
+
+		"yaml": {
+			input: input{
+				reader: strings.NewReader(`
+version: 1
+query: language:go
+num_projects: 5
+queries:
+  - query: "*"
+    backend: corpus
+    config:
+      archive: old.tar.zst
+`),
+				ext:     ".yml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{
+					Query:       "language:go",
+					NumProjects: 5,
+					Queries:     []QueryContents{{Query: "*", Backend: "corpus", Config: map[string]string{"archive": "old.tar.zst"}}},
+				},
+			},
+		},
+
+		"toml": {
+			input: input{
+				reader: strings.NewReader(`
+query = "language:go"
+num_projects = 5
+clean = true
+
+[[queries]]
+query = "*"
+operation = "difference"
+`),
+				ext:     ".toml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{
+					Query:       "language:go",
+					NumProjects: 5,
+					Clean:       true,
+					Queries:     []QueryContents{{Query: "*", Operation: "difference"}},
+				},
+			},
+		},
+
+		"unknown_field": {
+			input: input{
+				reader:  strings.NewReader("{\n  \"querry\": \"language:go\"\n}"),
+				ext:     ".json",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("line 2, column 3: unknown field 'querry'"),
+			},
+		},
+
+		"unknown_nested_field": {
+			input: input{
+				reader:  strings.NewReader("queries:\n  - query: a\n    operaton: union\n"),
+				ext:     ".yaml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("line 3, column 5: unknown field 'queries[0].operaton'"),
+			},
+		},
+
+		"unknown_toml_field": {
+			input: input{
+				reader:  strings.NewReader("query = \"a\"\nclen = true\n"),
+				ext:     ".toml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{Query: "a"},
+				err:     errors.New("line 2, column 1: unknown field 'clen'"),
+			},
+		},
+
+		"invalid_type": {
+			input: input{
+				reader:  strings.NewReader("num_projects: ten\n"),
+				ext:     ".yaml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("line 1, column 15: num_projects: expected a int, not 'ten'"),
+			},
+		},
+
+		"syntax_error": {
+			input: input{
+				reader:  strings.NewReader("query = \"a\"\nclean = yes\n"),
+				ext:     ".toml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("line 2, column 9: keys cannot contain new lines"),
+			},
+		},
+
+		"unsupported_version": {
+			input: input{
+				reader:  strings.NewReader("version: 2\nquery: a\n"),
+				ext:     ".yaml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("line 1, column 10: version: unsupported config version 2 (the latest is 1)"),
+			},
+		},
+
+		"experiments": {
+			input: input{
+				reader:  strings.NewReader("query: a\nexperiments:\n  vet:\n    command: go vet ./...\n"),
+				ext:     ".yaml",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("the config file has experiments (vet): select one with --experiment or run them with 'neighbor run --experiments=<file>'"),
+			},
+		},
+
+		"unsupported_extension": {
+			input: input{
+				reader:  strings.NewReader("query=a"),
+				ext:     ".ini",
+				content: &Contents{},
+			},
+			want: want{
+				content: Contents{},
+				err:     errors.New("unsupported config file extension '.ini' (.json, .yaml, .yml or .toml)"),
+			},
+		},
 	}
 
 	for name, tt := range tests {
 		tt := tt
 		t.Run(name, func(t *testing.T) {
-			gotErr := parse(tt.input.reader, tt.input.content)
+			gotErr := parse(tt.input.reader, tt.input.ext, tt.input.content)
 
 			if diff := cmp.Diff(tt.want.content, *tt.input.content); diff != "" {
 				t.Errorf("parse() mismatch (-want +got):\n%s", diff)
@@ -76,3 +291,211 @@ func Test_parse(t *testing.T) {
 		})
 	}
 }
+
+func Test_loadContents(t *testing.T) {
+	type input struct {
+		file       string
+		experiment string
+		env        map[string]string
+		args       []string
+	}
+
+	type want struct {
+		content Contents
+		sources map[string]Source
+		err     error
+	}
+
+	defaults := Contents{
+		SearchType:  "project",
+		NumProjects: 10,
+		ProjectsDir: "_external_projects",
+		Clean:       true,
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"defaults": {
+			input: input{},
+			want: want{
+				content: defaults,
+				sources: map[string]Source{"num_projects": SourceDefault, "clean": SourceDefault},
+			},
+		},
+
+		"missing_fields_keep_defaults": {
+			input: input{file: `{"query": "language:go"}`},
+			want: want{
+				content: Contents{SearchType: "project", Query: "language:go", NumProjects: 10, ProjectsDir: "_external_projects", Clean: true},
+				sources: map[string]Source{"query": SourceFile, "clean": SourceDefault},
+			},
+		},
+
+		"precedence": {
+			input: input{
+				file: `{"query": "file", "num_projects": 5, "search_type": "code", "clean": false}`,
+				env:  map[string]string{"NEIGHBOR_NUM_PROJECTS": "7", "NEIGHBOR_SEARCH_TYPE": "version"},
+				args: []string{"--search_type=project"},
+			},
+			want: want{
+				content: Contents{SearchType: "project", Query: "file", NumProjects: 7, ProjectsDir: "_external_projects", Clean: false},
+				sources: map[string]Source{"query": SourceFile, "num_projects": SourceEnv, "search_type": SourceFlag, "clean": SourceFile},
+			},
+		},
+
+		"invalid_env": {
+			input: input{env: map[string]string{"NEIGHBOR_CLEAN": "maybe"}},
+			want: want{
+				content: defaults,
+				err:     errors.New("invalid value for NEIGHBOR_CLEAN: strconv.ParseBool: parsing \"maybe\": invalid syntax"),
+			},
+		},
+
+		"invalid_file_search_type": {
+			input: input{file: "{\n  \"query\": \"a\",\n  \"search_type\": \"repo\"\n}"},
+			want: want{
+				content: Contents{SearchType: "repo", Query: "a", NumProjects: 10, ProjectsDir: "_external_projects", Clean: true},
+				err:     errors.New("invalid config file '$FILE': line 3, column 3: search_type: unsupported search type 'repo'"),
+			},
+		},
+
+		"invalid_env_search_type": {
+			input: input{env: map[string]string{"NEIGHBOR_SEARCH_TYPE": "repo"}},
+			want: want{
+				content: Contents{SearchType: "repo", NumProjects: 10, ProjectsDir: "_external_projects", Clean: true},
+				err:     errors.New("invalid NEIGHBOR_SEARCH_TYPE: search_type: unsupported search type 'repo'"),
+			},
+		},
+
+		"experiment": {
+			input: input{
+				file: `
+x-go: &go
+  search_type: code
+  query: language:go
+num_projects: 5
+experiments:
+  vet:
+    <<: *go
+    command: go vet ./...
+  count:
+    <<: *go
+    num_projects: 50
+    query: language:rust
+`,
+				experiment: "count",
+			},
+			want: want{
+				content: Contents{SearchType: "code", Query: "language:rust", NumProjects: 50, ProjectsDir: "_external_projects", Clean: true},
+				sources: map[string]Source{"search_type": SourceFile, "num_projects": SourceFile, "command": SourceDefault},
+			},
+		},
+
+		"unknown_experiment": {
+			input: input{
+				file:       "experiments:\n  vet:\n    command: go vet ./...\n  test:\n    command: go test ./...\n",
+				experiment: "lint",
+			},
+			want: want{
+				content: defaults,
+				err:     errors.New("error parsing config file '$FILE': unknown experiment 'lint' (vet, test)"),
+			},
+		},
+
+		"unselected_experiment": {
+			input: input{
+				file: "num_projects: 5\nexperiments:\n  vet:\n    command: go vet ./...\n  test:\n    command: go test ./...\n",
+			},
+			want: want{
+				content: defaults,
+				err:     errors.New("error parsing config file '$FILE': the config file has experiments (vet, test): select one with --experiment or run them with 'neighbor run --experiments=<file>'"),
+			},
+		},
+
+		"unknown_experiment_field": {
+			input: input{
+				file:       "experiments:\n  vet:\n    comand: go vet ./...\n",
+				experiment: "vet",
+			},
+			want: want{
+				content: defaults,
+				err:     errors.New("error parsing config file '$FILE': line 3, column 5: unknown field 'experiments.vet.comand'"),
+			},
+		},
+
+		"conflicting_flags": {
+			input: input{args: []string{"--plain_retrieve"}},
+			want: want{
+				content: Contents{SearchType: "project", NumProjects: 10, ProjectsDir: "_external_projects", PlainRetrieve: true, Clean: true},
+				err:     errors.New("invalid config: plain_retrieve: cannot be combined with clean"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			var fp string
+			if len(tt.input.file) != 0 {
+				dir, err := ioutil.TempDir("", "neighbor-config")
+				if err != nil {
+					t.Fatalf("failed to create directory: %+v", err)
+				}
+				defer os.RemoveAll(dir)
+
+				fp = filepath.Join(dir, "config.yaml")
+				if err := ioutil.WriteFile(fp, []byte(tt.input.file), 0644); err != nil {
+					t.Fatalf("failed to write config file: %+v", err)
+				}
+			}
+
+			lookupEnv = func(key string) (string, bool) {
+				v, ok := tt.input.env[key]
+				return v, ok
+			}
+			defer func() { lookupEnv = os.LookupEnv }()
+
+			var c Contents
+			fs := flag.NewFlagSet(name, flag.ContinueOnError)
+			searchFlags(fs, &c)
+			projectsDirFlag(fs, &c)
+			legacyFlags(fs, &c)
+			if err := fs.Parse(tt.input.args); err != nil {
+				t.Fatalf("failed to parse flags: %+v", err)
+			}
+
+			got, gotErr := loadContents(fs, fp, tt.input.experiment, &c)
+
+			wantErr := tt.want.err
+			if wantErr != nil {
+				wantErr = errors.New(strings.Replace(wantErr.Error(), "$FILE", fp, 1))
+			}
+
+			// only the flag defaults that the tests care about are compared.
+			c.CacheTTL, c.SampleStrategy, c.SampleStratum, c.SamplePool = "", "", "", 0
+			if diff := cmp.Diff(tt.want.content, c); diff != "" {
+				t.Errorf("loadContents() mismatch (-want +got):\n%s", diff)
+			}
+
+			for field, want := range tt.want.sources {
+				if got[field] != want {
+					t.Errorf("loadContents() source of '%s' \n\tgot: '%+v'\n\twant: '%+v'", field, got[field], want)
+				}
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, wantErr); !ok {
+				t.Errorf("loadContents() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, wantErr)
+			}
+		})
+	}
+}
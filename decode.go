@@ -0,0 +1,453 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configVersion is the latest version of the schema of config files. Config files
+// without a version are the latest version.
+const configVersion = 1
+
+// ConfigError is an error in a config file, at the line and column of the value
+// that caused it, if known.
+type ConfigError struct {
+	Line   int
+	Column int
+	// Field is the path of the field that caused the error (e.g., queries[1].backend).
+	Field string
+	Msg   string
+}
+
+func (e *ConfigError) Error() string {
+	msg := e.Msg
+	if len(e.Field) != 0 {
+		msg = fmt.Sprintf("%s: %s", e.Field, msg)
+	}
+
+	switch {
+	case e.Line == 0:
+		return msg
+	case e.Column == 0:
+		return fmt.Sprintf("line %d: %s", e.Line, msg)
+	}
+
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, msg)
+}
+
+// nodeError returns a ConfigError at the position of n.
+func nodeError(n *yaml.Node, field string, format string, a ...interface{}) *ConfigError {
+	return &ConfigError{Line: n.Line, Column: n.Column, Field: field, Msg: fmt.Sprintf(format, a...)}
+}
+
+var (
+	yamlErrorRe = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+	tomlErrorRe = regexp.MustCompile(`^\((\d+), (\d+)\): (.*)$`)
+)
+
+// decodeFile decodes the config file in r, whose format is that of its extension
+// (.json, .yaml, .yml or .toml), and returns its top-level mapping. JSON is a
+// subset of YAML, so JSON config files are decoded as YAML, which keeps the
+// position of every value.
+func decodeFile(r io.Reader, ext string) (*yaml.Node, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var n *yaml.Node
+	switch strings.ToLower(ext) {
+	case ".json", ".yaml", ".yml":
+		n, err = decodeYAML(b)
+	case ".toml":
+		n, err = decodeTOML(b)
+	default:
+		return nil, fmt.Errorf("unsupported config file extension '%s' (.json, .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkVersion(n); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func decodeYAML(b []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		if m := yamlErrorRe.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, &ConfigError{Line: line, Msg: m[2]}
+		}
+		return nil, &ConfigError{Msg: err.Error()}
+	}
+
+	// an empty file sets nothing.
+	if doc.Kind == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode}, nil
+	}
+
+	n := resolve(&doc)
+	if n.Kind != yaml.MappingNode {
+		return nil, nodeError(n, "", "expected a mapping of fields")
+	}
+
+	return n, nil
+}
+
+func decodeTOML(b []byte) (*yaml.Node, error) {
+	t, err := toml.LoadBytes(b)
+	if err != nil {
+		if m := tomlErrorRe.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			col, _ := strconv.Atoi(m[2])
+			return nil, &ConfigError{Line: line, Column: col, Msg: m[3]}
+		}
+		return nil, &ConfigError{Msg: err.Error()}
+	}
+
+	return tomlNode(t, t.GetPosition(""))
+}
+
+// tomlNode converts a value of a TOML tree to a YAML node, so that config files of
+// every format are decoded the same way. Values of arrays are at the position of
+// their key because the TOML tree does not keep their position.
+func tomlNode(v interface{}, pos toml.Position) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case *toml.Tree:
+		n := &yaml.Node{Kind: yaml.MappingNode, Line: pos.Line, Column: pos.Col}
+
+		keys := v.Keys()
+		sort.Slice(keys, func(i, j int) bool {
+			a, b := v.GetPosition(keys[i]), v.GetPosition(keys[j])
+			return a.Line < b.Line || (a.Line == b.Line && a.Col < b.Col)
+		})
+
+		for _, k := range keys {
+			p := v.GetPosition(k)
+
+			val, err := tomlNode(v.Get(k), p)
+			if err != nil {
+				return nil, err
+			}
+
+			n.Content = append(n.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k, Line: p.Line, Column: p.Col}, val)
+		}
+
+		return n, nil
+	case []*toml.Tree:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Line: pos.Line, Column: pos.Col}
+		for _, t := range v {
+			e, err := tomlNode(t, t.GetPosition(""))
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, e)
+		}
+
+		return n, nil
+	case []interface{}:
+		n := &yaml.Node{Kind: yaml.SequenceNode, Line: pos.Line, Column: pos.Col}
+		for _, e := range v {
+			en, err := tomlNode(e, pos)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, en)
+		}
+
+		return n, nil
+	}
+
+	var n yaml.Node
+	if err := n.Encode(v); err != nil {
+		return nil, &ConfigError{Line: pos.Line, Column: pos.Col, Msg: err.Error()}
+	}
+	n.Line, n.Column = pos.Line, pos.Col
+
+	return &n, nil
+}
+
+// checkVersion checks that the version of the config file of the mapping n, if it
+// has one, is supported and removes it from n, because it is not a field of
+// Contents.
+func checkVersion(n *yaml.Node) error {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != "version" {
+			continue
+		}
+
+		v := resolve(n.Content[i+1])
+
+		var version int
+		if err := v.Decode(&version); err != nil {
+			return nodeError(v, "version", "expected an integer")
+		}
+
+		if version < 1 || version > configVersion {
+			return nodeError(v, "version", "unsupported config version %d (the latest is %d)", version, configVersion)
+		}
+
+		n.Content = append(n.Content[:i], n.Content[i+2:]...)
+		return nil
+	}
+
+	return nil
+}
+
+// resolve returns the node that n refers to, i.e., the content of a document or
+// the anchored node of an alias.
+func resolve(n *yaml.Node) *yaml.Node {
+	for {
+		switch {
+		case n.Kind == yaml.DocumentNode && len(n.Content) != 0:
+			n = n.Content[0]
+		case n.Kind == yaml.AliasNode && n.Alias != nil:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+}
+
+// pair is a key and value of a mapping.
+type pair struct {
+	key   *yaml.Node
+	value *yaml.Node
+}
+
+// pairs returns the keys and values of the mapping n. The pairs of mappings that
+// are merged into n with the << key (e.g., <<: *defaults) come first, so that the
+// pairs of n override them.
+func pairs(n *yaml.Node) []pair {
+	n = resolve(n)
+
+	var merged, own []pair
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Tag != "!!merge" {
+			own = append(own, pair{key: k, value: v})
+			continue
+		}
+
+		v = resolve(v)
+		if v.Kind == yaml.SequenceNode {
+			for _, m := range v.Content {
+				merged = append(merged, pairs(m)...)
+			}
+			continue
+		}
+		merged = append(merged, pairs(v)...)
+	}
+
+	return append(merged, own...)
+}
+
+// decodeNode decodes n into v, which is named field. Struct fields are matched by
+// the name of their JSON key and unknown fields are rejected.
+func decodeNode(n *yaml.Node, v reflect.Value, field string) error {
+	n = resolve(n)
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nodeError(n, field, "expected a mapping")
+		}
+
+		fields := structFields(v)
+		for _, p := range pairs(n) {
+			f, ok := fields[p.key.Value]
+			if !ok {
+				return nodeError(p.key, "", "unknown field '%s'", joinField(field, p.key.Value))
+			}
+
+			if err := decodeNode(p.value, f, joinField(field, p.key.Value)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case reflect.Slice:
+		if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+
+		if n.Kind != yaml.SequenceNode {
+			return nodeError(n, field, "expected a list")
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(n.Content), len(n.Content))
+		for i, e := range n.Content {
+			if err := decodeNode(e, s.Index(i), fmt.Sprintf("%s[%d]", field, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+
+		return nil
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return nodeError(n, field, "expected a mapping")
+		}
+
+		m := reflect.MakeMapWithSize(v.Type(), len(n.Content)/2)
+		for _, p := range pairs(n) {
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := decodeNode(p.value, e, joinField(field, p.key.Value)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(p.key.Value), e)
+		}
+		v.Set(m)
+
+		return nil
+	}
+
+	if n.Kind != yaml.ScalarNode {
+		return nodeError(n, field, "expected a %s", v.Kind())
+	}
+
+	if err := n.Decode(v.Addr().Interface()); err != nil {
+		return nodeError(n, field, "expected a %s, not '%s'", v.Kind(), n.Value)
+	}
+
+	return nil
+}
+
+// structFields returns the fields of the struct v by the name of their JSON key.
+func structFields(v reflect.Value) map[string]reflect.Value {
+	t := v.Type()
+
+	res := make(map[string]reflect.Value, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if len(name) == 0 || name == "-" {
+			continue
+		}
+
+		res[name] = v.Field(i)
+	}
+
+	return res
+}
+
+func joinField(parent string, name string) string {
+	if len(parent) == 0 {
+		return name
+	}
+
+	return parent + "." + name
+}
+
+// experimentsField is the field of config files that names experiments, each of
+// which has the fields of Contents.
+const experimentsField = "experiments"
+
+// extensionPrefix prefixes the top-level fields of config files that are ignored,
+// e.g., x-defaults, which can hold YAML anchors that experiments merge.
+const extensionPrefix = "x-"
+
+// flatten returns the content of a mapping of ps.
+func flatten(ps []pair) []*yaml.Node {
+	res := make([]*yaml.Node, 0, 2*len(ps))
+	for _, p := range ps {
+		res = append(res, p.key, p.value)
+	}
+
+	return res
+}
+
+// splitExperiments returns the pairs of the top-level mapping n of a config file
+// other than its experiments, which are the shared defaults of the experiments,
+// and the mapping of its experiments, if it has any.
+func splitExperiments(n *yaml.Node) ([]pair, *yaml.Node, error) {
+	var fields []pair
+	var experiments *yaml.Node
+
+	for _, p := range pairs(n) {
+		// extension fields only hold anchors that the other fields refer to.
+		if strings.HasPrefix(p.key.Value, extensionPrefix) {
+			continue
+		}
+
+		if p.key.Value != experimentsField {
+			fields = append(fields, p)
+			continue
+		}
+
+		experiments = resolve(p.value)
+		if experiments.Kind != yaml.MappingNode {
+			return nil, nil, nodeError(experiments, experimentsField, "expected a mapping of experiments by name")
+		}
+
+		for _, e := range pairs(experiments) {
+			if resolve(e.value).Kind != yaml.MappingNode {
+				return nil, nil, nodeError(e.value, joinField(experimentsField, e.key.Value), "expected a mapping")
+			}
+		}
+	}
+
+	return fields, experiments, nil
+}
+
+// experimentNames returns the names of the experiments, in the order that they
+// are in the config file.
+func experimentNames(experiments *yaml.Node) []string {
+	if experiments == nil {
+		return nil
+	}
+
+	var names []string
+	for _, p := range pairs(experiments) {
+		names = append(names, p.key.Value)
+	}
+
+	return names
+}
+
+// findExperiment returns the mapping of the experiment named name.
+func findExperiment(experiments *yaml.Node, name string) (*yaml.Node, error) {
+	names := experimentNames(experiments)
+	if len(names) == 0 {
+		return nil, fmt.Errorf("unknown experiment '%s' (the config file has no experiments)", name)
+	}
+
+	// later experiments with the same name override earlier ones, like fields.
+	var res *yaml.Node
+	for _, p := range pairs(experiments) {
+		if p.key.Value == name {
+			res = p.value
+		}
+	}
+
+	if res == nil {
+		return nil, fmt.Errorf("unknown experiment '%s' (%s)", name, strings.Join(names, ", "))
+	}
+
+	return res, nil
+}
+
+// unselectedExperimentsError is returned when a config file with experiments is
+// loaded without selecting one of them, which would silently ignore their fields.
+func unselectedExperimentsError(experiments *yaml.Node) error {
+	names := experimentNames(experiments)
+	if len(names) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("the config file has experiments (%s): select one with --experiment or run them with 'neighbor run --experiments=<file>'", strings.Join(names, ", "))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/mccurdyc/neighbor/sdk/sample"
+)
+
+// The flags of each phase are registered in groups, so that every command only
+// accepts the flags of the phases that it runs. Flags are bound to the fields of
+// Contents, which take precedence over the config file and the environment (see
+// loadContents).
+
+func authFlags(fs *flag.FlagSet, c *Contents) {
+	fs.StringVar(&c.AuthToken, "auth_token", "", "Your personal GitHub access token. This is required to access private repositories and increases rate limits. Multiple comma-separated tokens are rotated to spread the API quota.")
+	fs.StringVar(&c.AuthTokenFile, "auth_token_file", "", "Filepath of a file containing a GitHub access token. Without an auth_token, credentials are read from this file, the GITHUB_TOKEN environment variable, the netrc file or the Git credential helper, in that order. Tokens from this file or the environment are only sent to GitHub and the github_base_url host.")
+	fs.StringVar(&c.GitHubAppID, "github_app_id", "", "The ID of a GitHub App to authenticate as instead of using an access token.")
+	fs.StringVar(&c.GitHubInstallationID, "github_installation_id", "", "The ID of the installation of the GitHub App.")
+	fs.StringVar(&c.GitHubPrivateKeyFile, "github_private_key_file", "", "Filepath of the PEM encoded private key of the GitHub App.")
+	fs.StringVar(&c.GitHubBaseURL, "github_base_url", "", "The url of a GitHub Enterprise Server instance to search instead of github.com.")
+	fs.StringVar(&c.GitHubUploadURL, "github_upload_url", "", "The upload url of a GitHub Enterprise Server instance (defaults to one derived from github_base_url).")
+}
+
+func corpusFlags(fs *flag.FlagSet, c *Contents) {
+	fs.StringVar(&c.Corpus, "corpus", "", "Filepath of a previously exported archive to search and retrieve projects from instead of GitHub.")
+	fs.StringVar(&c.CorpusManifest, "corpus_manifest", "", "Filepath of a published manifest to verify the projects of the corpus archive against.")
+}
+
+func searchFlags(fs *flag.FlagSet, c *Contents) {
+	fs.StringVar(&c.CacheDir, "cache_dir", "", "Directory to cache GitHub API responses in. Cached responses are revalidated with conditional requests, which do not count against rate limits.")
+	fs.StringVar(&c.CacheTTL, "cache_ttl", "0s", "How long cached GitHub API responses are reused without revalidation (e.g., 1h).")
+	fs.StringVar(&c.SearchType, "search_type", "project", "The type of search to perform.")
+	fs.StringVar(&c.Query, "query", "", "The search query to execute.")
+	fs.IntVar(&c.NumProjects, "num_projects", 10, "The number of _desired_ projects to obtain.")
+	fs.BoolVar(&c.CollapseForks, "collapse_forks", false, "Collapse forks returned from the search onto the repository that they were forked from.")
+	fs.BoolVar(&c.DedupeContent, "dedupe_content", false, "Collapse repositories returned from the search with the same content (i.e., tree hash of the latest commit), such as mirrors, onto the first one found.")
+	fs.StringVar(&c.Filter, "filter", "", "An expression that projects returned from the search must match to be retrieved (e.g., \"!fork && stars > 50 && license in ['mit', 'apache-2.0']\").")
+	fs.IntVar(&c.Sample, "sample", 0, "The number of projects to sample at random from the sample_pool search results (0 disables sampling).")
+	fs.IntVar(&c.SamplePool, "sample_pool", 1000, "The number of search results that projects are sampled from, instead of num_projects, when sampling. GitHub returns at most 1000 results per search.")
+	fs.Int64Var(&c.Seed, "seed", 0, "The seed used to sample projects. The same seed and search results always result in the same sample (0 picks a random seed, which is logged and recorded in the export).")
+	fs.StringVar(&c.SampleStrategy, "sample_strategy", sample.StrategyUniform, "How projects are sampled (uniform or stratified).")
+	fs.StringVar(&c.SampleStratum, "sample_stratum", "language", "The metadata field that projects are stratified by when using stratified sampling (language, license, fork, archived or stars).")
+}
+
+func retrieveFlags(fs *flag.FlagSet, c *Contents) {
+	fs.BoolVar(&c.Submodules, "submodules", false, "Recursively initialize the Git submodules of each project.")
+	fs.IntVar(&c.SubmoduleDepth, "submodule_depth", 10, "The maximum depth of nested Git submodules to initialize.")
+	fs.BoolVar(&c.LFS, "lfs", false, "Resolve Git LFS pointer files of each project.")
+	fs.IntVar(&c.MaxRetrievalAttempts, "max_retrieval_attempts", 3, "The maximum number of attempts to retrieve a project when retrieval fails transiently.")
+	fs.Int64Var(&c.MaxRepoSize, "max_repo_size", 0, "The maximum size in bytes of a project, as reported by the search backend, to retrieve (0 is unlimited).")
+	fs.Int64Var(&c.MaxFiles, "max_files", 0, "The maximum number of files written when retrieving a project (0 is unlimited).")
+	fs.Int64Var(&c.MaxBytes, "max_bytes", 0, "The maximum number of bytes written when retrieving a project (0 is unlimited).")
+	fs.StringVar(&c.GoSumDB, "gosumdb", "sum.golang.org", "The checksum database that modules retrieved from a Go module proxy are verified against, in the format of GOSUMDB (i.e., a known name or name+key [url]), or off.")
+	fs.StringVar(&c.GoSumFile, "go_sum_file", "", "Filepath of a go.sum file that modules retrieved from a Go module proxy are verified against instead of the checksum database.")
+	fs.StringVar(&c.LocalMode, "local_mode", "auto", "How local projects are placed in the projects directory (auto, reflink, hardlink, copy or clone). auto tries reflink, then clone for Git repositories without uncommitted changes, then copy.")
+	fs.StringVar(&c.Export, "export", "", "Filepath of an archive (.tar, .tar.gz, .tgz or .tar.zst) to export the retrieved projects to, before the command runs on them.")
+}
+
+func projectsDirFlag(fs *flag.FlagSet, c *Contents) {
+	fs.StringVar(&c.ProjectsDir, "projects_directory", "_external_projects", "Where the projects should be stored locally and found for evalutation.")
+}
+
+func commandFlag(fs *flag.FlagSet, c *Contents) {
+	fs.StringVar(&c.Command, "command", "", "The command to execute on each project returned from a search query.")
+}
+
+// legacyFlags are the flags of running every phase at once, which the
+// subcommands do not need because they never delete the projects directory.
+func legacyFlags(fs *flag.FlagSet, c *Contents) {
+	fs.BoolVar(&c.PlainRetrieve, "plain_retrieve", false, "Whether projects should just be retrieved and not evaluated.")
+	fs.BoolVar(&c.Clean, "clean", true, "Delete the projects directory after running the command against each project.")
+}
+
+// experimentFlag returns the name of the experiment of the config file to use.
+func experimentFlag(fs *flag.FlagSet) *string {
+	return fs.String("experiment", "", "The name of the experiment of the config file whose fields override the fields that it shares with the other experiments.")
+}
+
+// resumeFlag returns whether to resume the run journaled in the projects
+// directory.
+func resumeFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("resume", false, "Resume an interrupted run from the journal of the projects directory, skipping the projects that were retrieved, or run, successfully and retrying the others.")
+}
+
+// defaultContents returns the contents with the default value of every flag.
+func defaultContents() Contents {
+	var c Contents
+
+	fs := flag.NewFlagSet("defaults", flag.ContinueOnError)
+	authFlags(fs, &c)
+	corpusFlags(fs, &c)
+	searchFlags(fs, &c)
+	retrieveFlags(fs, &c)
+	projectsDirFlag(fs, &c)
+	commandFlag(fs, &c)
+	legacyFlags(fs, &c)
+
+	return c
+}
+
+// newFlagSet returns the flag set of a command, which includes the logging flags.
+func newFlagSet(name string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "\nUsage: %s\n\n", usage)
+		fs.PrintDefaults()
+		fmt.Fprint(fs.Output(), "\n")
+	}
+
+	return fs
+}
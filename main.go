@@ -1,168 +1,223 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
-	"os/signal"
 	"path/filepath"
-	"syscall"
+	"sort"
+	"strings"
 
 	"github.com/golang/glog"
+	gogit "gopkg.in/src-d/go-git.v4"
 
-	"github.com/mccurdyc/neighbor/builtin/retrieval/git"
-	"github.com/mccurdyc/neighbor/builtin/run/binary"
-	"github.com/mccurdyc/neighbor/builtin/search/github"
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
 	"github.com/mccurdyc/neighbor/sdk/run"
-	"github.com/mccurdyc/neighbor/sdk/search"
 )
 
 func main() {
-	fp := flag.String("file", "", "Absolute filepath to the config file.")
-	tkn := flag.String("auth_token", "", "Your personal GitHub access token. This is required to access private repositories and increases rate limits.")
-	searchType := flag.String("search_type", "project", "The type of search to perform.")
-	query := flag.String("query", "", "The search query to execute.")
-	command := flag.String("command", "", "The command to execute on each project returned from a search query.")
-	projectsDir := flag.String("projects_directory", "_external_projects", "Where the projects should be stored locally and found for evalutation.")
-	numProjects := flag.Int("num_projects", 10, "The number of _desired_ projects to obtain.")
-	plainRetrieve := flag.Bool("plain_retrieve", false, "Whether projects should just be retrieved and not evaluated.")
-	clean := flag.Bool("clean", true, "Delete the projects directory after running the command against each project.")
+	if len(os.Args) > 1 {
+		if cmd, ok := lookupCommand(os.Args[1]); ok {
+			// the flags of a subcommand, including the logging flags, are parsed by
+			// its own flag set, but glog requires the command line to be parsed.
+			flag.CommandLine.Parse(nil)
+
+			if err := cmd.run(os.Args[2:]); err != nil {
+				glog.Exit(err)
+			}
+
+			glog.Flush()
+			return
+		}
+	}
+
+	var c Contents
+
+	fp := flag.String("file", "", "Absolute filepath to the config file (.json, .yaml, .yml or .toml).")
+	authFlags(flag.CommandLine, &c)
+	corpusFlags(flag.CommandLine, &c)
+	searchFlags(flag.CommandLine, &c)
+	retrieveFlags(flag.CommandLine, &c)
+	projectsDirFlag(flag.CommandLine, &c)
+	commandFlag(flag.CommandLine, &c)
+	legacyFlags(flag.CommandLine, &c)
+	resume := resumeFlag(flag.CommandLine)
 	help := flag.Bool("help", false, "Print this help menu.")
 
+	flag.Usage = usage
 	flag.Parse()
 
-	if *help ||
-		(*fp == "" && (*query == "" || *searchType == "")) {
+	if *help {
 		usage()
 		os.Exit(1)
 	}
 
-	cfg := NewCfg(*fp)
+	if _, err := loadContents(flag.CommandLine, *fp, "", &c); err != nil {
+		glog.Exitf("failed to load config: %+v", err)
+	}
 
-	if len(*fp) != 0 {
-		cfg.Parse()
+	if (c.Query == "" && len(c.Queries) == 0 && c.Corpus == "") || c.SearchType == "" {
+		usage()
+		os.Exit(1)
+	}
 
-		tkn = &cfg.Contents.AuthToken
-		searchType = &cfg.Contents.SearchType
-		query = &cfg.Contents.Query
-		command = &cfg.Contents.Command
-		numProjects = &cfg.Contents.NumProjects
-		projectsDir = &cfg.Contents.ProjectsDir
-		plainRetrieve = &cfg.Contents.PlainRetrieve
-		clean = &cfg.Contents.Clean
+	if !c.PlainRetrieve && c.Command == "" {
+		glog.Exitf("cannot disable plain_retrieve and have an empty command (or use the retrieve subcommand)")
 	}
 
-	if !*plainRetrieve && *command == "" {
-		glog.Exitf("cannot disable `plain_retrieve` and have an empty `command`")
+	// the journal of the run to resume would have been deleted with the projects.
+	if *resume && c.Clean {
+		glog.Exitf("cannot resume and clean the projects directory (use --clean=false)")
 	}
 
-	if *plainRetrieve && *clean {
-		glog.Exitf("cannot enable `plain_retrieve` and `clean`")
+	ctx := signalContext()
+
+	workingDir, err := os.Getwd()
+	if err != nil {
+		glog.Exitf("failed to get working directory: %+v", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	mkdir := os.Mkdir
+	if *resume {
+		mkdir = os.MkdirAll
+	}
 
-	// listen for signals such as SIGINT (^C, CONTROL-C)
-	go func() {
-		ch := make(chan os.Signal, 1)
+	err = mkdir(c.ProjectsDir, os.ModePerm)
+	if err != nil {
+		glog.Exitf("failed to create project directory: %+v", err)
+	}
 
-		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
-		defer signal.Stop(ch)
+	if c.Clean {
+		defer cleanUp(c.ProjectsDir)
+	}
 
-		select {
-		case <-ch:
-			cancel()
-			os.Exit(130)
-		}
-	}()
+	root := filepath.Join(workingDir, c.ProjectsDir)
 
-	workingDir, err := os.Getwd()
+	m, err := resumeOrSearch(ctx, &c, root, *resume)
 	if err != nil {
-		glog.Exitf("failed to get working directory: %+v", err)
+		cleanUp(c.ProjectsDir)
+		glog.Exit(err)
 	}
 
-	err = os.Mkdir(*projectsDir, os.ModePerm)
+	retriever, err := newRetrievers(ctx, &c, m)
 	if err != nil {
-		glog.Exitf("failed to create project directory: %+v", err)
+		cleanUp(c.ProjectsDir)
+		glog.Exit(err)
+	}
+
+	var cmd run.Backend
+	if !c.PlainRetrieve {
+		cmd, err = newRunner(ctx, &c)
+		if err != nil {
+			cleanUp(c.ProjectsDir)
+			glog.Exit(err)
+		}
 	}
 
-	if *clean {
-		defer cleanUp(*projectsDir)
+	j, err := openJournal(root)
+	if err != nil {
+		cleanUp(c.ProjectsDir)
+		glog.Exit(err)
 	}
+	defer j.Close()
 
-	var method uint32
-	switch *searchType {
-	case "project", "projects":
-		method = search.Project
-	case "code":
-		method = search.Code
-	default:
-		glog.Exit("unsupported search type")
+	limits := retrieval.Limits{MaxSize: c.MaxRepoSize}
+	if err := retrieveProjects(ctx, j, retriever, limits, root, m, *resume); err != nil {
+		glog.Error(err)
+		return
 	}
 
-	searchConfig := search.BackendConfig{
-		SearchMethod: search.Method(method),
+	reportSkipped(os.Stderr, m)
+
+	// projects are exported before the command can modify them.
+	if len(c.Export) != 0 && !ran(m) {
+		if err := exportProjects(c.Export, root, m); err != nil {
+			glog.Error(err)
+			return
+		}
 	}
 
-	if len(*tkn) != 0 {
-		searchConfig.AuthMethod = "token"
-		searchConfig.Config = map[string]string{"token": *tkn}
+	if cmd == nil {
+		return
 	}
 
-	githubSearch, err := github.Factory(ctx, &searchConfig)
+	if err := runProjects(ctx, j, cmd, root, m, *resume); err != nil {
+		glog.Error(err)
+	}
+}
+
+// headVersion returns the commit SHA checked out in dir or fallback if dir is not
+// a Git repository.
+func headVersion(dir string, fallback string) string {
+	repo, err := gogit.PlainOpen(dir)
 	if err != nil {
-		cleanUp(*projectsDir)
-		glog.Exitf("failed to create GitHub searcher: %+v", err)
+		return fallback
 	}
 
-	projects, err := githubSearch.Search(context.TODO(), *query, *numProjects)
+	ref, err := repo.Head()
 	if err != nil {
-		glog.Errorf("encountered error while searching GitHub for projects: %+v", err)
+		return fallback
 	}
 
-	var retrievalConfig retrieval.BackendConfig
-	if len(*tkn) != 0 {
-		retrievalConfig.AuthMethod = "token"
-		retrievalConfig.Config = map[string]string{"token": *tkn}
+	return ref.Hash().String()
+}
+
+// githubAppConfig returns the config values for authenticating as an installation
+// of a GitHub App.
+func githubAppConfig(appID string, installationID string, privateKeyFile string) map[string]string {
+	return map[string]string{
+		"app_id":           appID,
+		"installation_id":  installationID,
+		"private_key_file": privateKeyFile,
 	}
+}
 
-	gitClone, err := git.Factory(ctx, &retrievalConfig)
-	if err != nil {
-		cleanUp(*projectsDir)
-		glog.Exitf("error creating Git project retriever: %+v", err)
+// enterpriseAPIURL returns the API url of the GitHub Enterprise Server instance at u.
+func enterpriseAPIURL(u string) string {
+	u = strings.TrimSuffix(u, "/")
+	if strings.HasSuffix(u, "/api/v3") {
+		return u + "/"
 	}
 
-	var cmd run.Backend
-	if !*plainRetrieve {
-		cmd, err = binary.Factory(ctx, &run.BackendConfig{
-			Cmd:    *command,
-			Stdout: os.Stdout,
-			Stderr: os.Stderr,
-		})
-		if err != nil {
-			cleanUp(*projectsDir)
-			glog.Exitf("failed to handle command: %+v", err)
-		}
+	return u + "/api/v3/"
+}
+
+// credentialHosts returns the hosts, other than GitHub, that a token from the
+// token file or the environment is sent to, i.e., the GitHub Enterprise Server
+// instance at baseURL, if specified.
+func credentialHosts(baseURL string) []string {
+	u, err := url.Parse(baseURL)
+	if err != nil || len(u.Hostname()) == 0 {
+		return nil
 	}
 
-	for _, p := range projects {
-		dir := filepath.Join(workingDir, *projectsDir, p.Name())
-		err := gitClone.Retrieve(ctx, p.SourceLocation(), dir)
-		if err != nil {
-			glog.Errorf("error retrieving project ('%s): %+v", p.Name(), err)
-			continue
-		}
+	return []string{u.Hostname()}
+}
 
-		if *plainRetrieve {
-			continue
+// reportSkipped reports the projects of m that were skipped for exceeding a limit.
+func reportSkipped(w io.Writer, m *Manifest) {
+	skipped := make(map[string]string)
+	for _, p := range m.Projects {
+		if p.Retrieval != nil && p.Retrieval.Skipped {
+			skipped[p.Name] = p.Retrieval.Error
 		}
+	}
 
-		err = cmd.Run(ctx, dir)
-		if err != nil {
-			glog.Errorf("failed to run binary command in '%s': %+v", dir, err)
-		}
+	if len(skipped) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(skipped))
+	for name := range skipped {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "skipped %d project(s) for exceeding limits:\n", len(names))
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %s\n", name, skipped[name])
 	}
 }
 
@@ -175,9 +230,15 @@ func cleanUp(dir string) {
 	}
 }
 
-// usage prints the usage and the supported flags.
+// usage prints the usage, the subcommands and the flags of running every phase at
+// once.
 func usage() {
-	fmt.Fprint(flag.CommandLine.Output(), "\nUsage: neighbor (--file=<file> | --query=<string> (--command=<string> | --plain_retrieve)) [--auth_token=<github-access-token>] [--search_type=<repository|code>] [--projects_directory=<string>] [--num_projects=<int>] [--clean=<bool> | --plain_retrieve]\n\n")
+	fmt.Fprint(flag.CommandLine.Output(), "\nUsage: neighbor (--file=<file> | (--query=<string> | --corpus=<file>) (--command=<string> | --plain_retrieve)) [--auth_token=<github-access-token> | --auth_token_file=<file>] [--github_app_id=<id> --github_installation_id=<id> --github_private_key_file=<file>] [--github_base_url=<url> [--github_upload_url=<url>]] [--cache_dir=<dir> [--cache_ttl=<duration>]] [--search_type=<repository|code>] [--collapse_forks] [--dedupe_content] [--filter=<expression>] [--sample=<int> [--sample_pool=<int>] [--seed=<int>] [--sample_strategy=<uniform|stratified> [--sample_stratum=<field>]]] [--projects_directory=<string>] [--num_projects=<int>] [--clean=<bool> | --plain_retrieve] [--submodules [--submodule_depth=<int>]] [--lfs] [--max_retrieval_attempts=<int>] [--max_repo_size=<bytes>] [--max_files=<int>] [--max_bytes=<bytes>] [--gosumdb=<database|off> | --go_sum_file=<file>] [--local_mode=<auto|reflink|hardlink|copy|clone>] [--export=<file>] [--corpus_manifest=<file>] [--resume --clean=false]\n")
+	fmt.Fprint(flag.CommandLine.Output(), "       neighbor <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(flag.CommandLine.Output(), "  %-10s%s\n", c.name, c.synopsis)
+	}
+	fmt.Fprint(flag.CommandLine.Output(), "\nUse neighbor <command> --help for the flags of a command.\n\nFlags:\n")
 	flag.PrintDefaults()
 	fmt.Fprint(flag.CommandLine.Output(), "\n")
 }
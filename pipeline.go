@@ -0,0 +1,787 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+
+	corpusretrieval "github.com/mccurdyc/neighbor/builtin/retrieval/corpus"
+	"github.com/mccurdyc/neighbor/builtin/retrieval/git"
+	goproxyretrieval "github.com/mccurdyc/neighbor/builtin/retrieval/goproxy"
+	localretrieval "github.com/mccurdyc/neighbor/builtin/retrieval/local"
+	"github.com/mccurdyc/neighbor/builtin/retrieval/retry"
+	"github.com/mccurdyc/neighbor/builtin/run/binary"
+	corpussearch "github.com/mccurdyc/neighbor/builtin/search/corpus"
+	"github.com/mccurdyc/neighbor/builtin/search/github"
+	goproxysearch "github.com/mccurdyc/neighbor/builtin/search/goproxy"
+	localsearch "github.com/mccurdyc/neighbor/builtin/search/local"
+	"github.com/mccurdyc/neighbor/sdk/auth"
+	"github.com/mccurdyc/neighbor/sdk/corpus"
+	"github.com/mccurdyc/neighbor/sdk/filter"
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+	"github.com/mccurdyc/neighbor/sdk/run"
+	"github.com/mccurdyc/neighbor/sdk/sample"
+	"github.com/mccurdyc/neighbor/sdk/search"
+)
+
+// signalContext returns a context that is canceled on signals such as SIGINT (^C,
+// CONTROL-C) or SIGTERM, after which the program exits.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		ch := make(chan os.Signal, 1)
+
+		signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(ch)
+
+		select {
+		case <-ch:
+			cancel()
+			os.Exit(130)
+		}
+	}()
+
+	return ctx
+}
+
+// sampleOptions returns the sample options of c, or nil if sampling is disabled.
+// A random seed is picked if c does not specify one.
+func sampleOptions(c *Contents) (*sample.Options, error) {
+	if c.Sample == 0 {
+		return nil, nil
+	}
+
+	if c.SamplePool < 1 {
+		return nil, fmt.Errorf("invalid sample: sample_pool must be a positive integer")
+	}
+
+	opts := &sample.Options{
+		Strategy: c.SampleStrategy,
+		Size:     c.Sample,
+		Pool:     c.SamplePool,
+		Seed:     c.Seed,
+		Stratum:  c.SampleStratum,
+	}
+
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+
+	if opts.Strategy != sample.StrategyStratified {
+		opts.Stratum = ""
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sample: %+v", err)
+	}
+
+	return opts, nil
+}
+
+// newSearcher returns the search backend of c, which is a composite search if c
+// has queries, a corpus search if c has a corpus and a GitHub search otherwise.
+func newSearcher(ctx context.Context, c *Contents) (search.Backend, error) {
+	method, err := searchMethod(c.SearchType)
+	if err != nil {
+		return nil, err
+	}
+
+	// an explicit token or GitHub App takes precedence over the credential providers.
+	searchConfig := search.BackendConfig{
+		AuthMethod:   "credentials",
+		Credentials:  auth.DefaultChain(c.AuthTokenFile, credentialHosts(c.GitHubBaseURL)...),
+		SearchMethod: search.Method(method),
+		BaseURL:      c.GitHubBaseURL,
+		UploadURL:    c.GitHubUploadURL,
+	}
+
+	if len(c.AuthToken) != 0 {
+		searchConfig.AuthMethod = "token"
+		searchConfig.Config = map[string]string{"token": c.AuthToken}
+	}
+
+	if len(c.GitHubAppID) != 0 {
+		searchConfig.AuthMethod = "github_app"
+		searchConfig.Config = githubAppConfig(c.GitHubAppID, c.GitHubInstallationID, c.GitHubPrivateKeyFile)
+	}
+
+	if searchConfig.Config == nil {
+		searchConfig.Config = make(map[string]string)
+	}
+
+	if len(c.CacheDir) != 0 {
+		searchConfig.Config["cache_dir"] = c.CacheDir
+		searchConfig.Config["cache_ttl"] = c.CacheTTL
+	}
+
+	searchConfig.Config["collapse_forks"] = strconv.FormatBool(c.CollapseForks)
+	searchConfig.Config["dedupe_content"] = strconv.FormatBool(c.DedupeContent)
+
+	if len(c.Queries) != 0 {
+		s, err := newCompositeSearcher(ctx, searchConfig, c.SearchType, c.Queries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite searcher: %+v", err)
+		}
+		return s, nil
+	}
+
+	if len(c.Corpus) != 0 {
+		searchConfig.Config = map[string]string{"archive": c.Corpus, "manifest": c.CorpusManifest}
+
+		s, err := corpussearch.Factory(ctx, &searchConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create corpus searcher: %+v", err)
+		}
+		return &originSearcher{Backend: s, origin: newOrigin("corpus", searchConfig.Config)}, nil
+	}
+
+	s, err := github.Factory(ctx, &searchConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitHub searcher: %+v", err)
+	}
+
+	return &originSearcher{Backend: s, origin: newOrigin("github", nil)}, nil
+}
+
+// searchProjects executes the search of c and returns a manifest of the projects
+// that match the filter and are sampled. A failed search is logged, rather than
+// returned, so that the projects found before it failed are still used.
+func searchProjects(ctx context.Context, c *Contents) (*Manifest, error) {
+	var sel filter.Selector
+	if len(c.Filter) != 0 {
+		var err error
+		sel, err = filter.Parse(c.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse filter: %+v", err)
+		}
+	}
+
+	sampleOpts, err := sampleOptions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	searcher, err := newSearcher(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// a sample is drawn from a pool of search results, rather than the first
+	// num_projects, so that it is not biased toward the results ranked first.
+	n := c.NumProjects
+	if sampleOpts != nil {
+		n = sampleOpts.Pool
+	}
+
+	projects, err := searcher.Search(ctx, c.Query, n)
+	if err != nil {
+		glog.Errorf("encountered error while searching for projects: %+v", err)
+	}
+
+	if sel != nil {
+		n := len(projects)
+		projects = filter.Select(projects, sel)
+		glog.Infof("filtered out %d of %d project(s)", n-len(projects), n)
+	}
+
+	if sampleOpts != nil {
+		glog.Infof("sampling %d project(s) with seed %d", sampleOpts.Size, sampleOpts.Seed)
+
+		projects, err = sample.Sample(projects, *sampleOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sample projects: %+v", err)
+		}
+	}
+
+	m := &Manifest{
+		NeighborVersion: version,
+		Query:           c.Query,
+		Sample:          sampleOpts,
+		Projects:        make([]Project, 0, len(projects)),
+	}
+
+	for _, p := range projects {
+		m.Projects = append(m.Projects, newProject(p))
+	}
+
+	return m, nil
+}
+
+// originConfig lists, for each search backend, the config values that the
+// retrieval of the projects that it finds depends on.
+var originConfig = map[string][]string{
+	"corpus":  {"archive", "manifest"},
+	"goproxy": {"proxy"},
+}
+
+// newOrigin returns the origin of the projects found by the search backend name
+// with config.
+func newOrigin(name string, config map[string]string) Origin {
+	o := Origin{Backend: name}
+
+	for _, k := range originConfig[name] {
+		if v := config[k]; len(v) != 0 {
+			if o.Config == nil {
+				o.Config = make(map[string]string)
+			}
+			o.Config[k] = v
+		}
+	}
+
+	return o
+}
+
+// key identifies the retrieval backend of the projects of o.
+func (o Origin) key() string {
+	keys := make([]string, 0, len(o.Config))
+	for k := range o.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(o.Backend)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%s", k, o.Config[k])
+	}
+
+	return b.String()
+}
+
+// originSearcher records the origin of each project that it finds.
+type originSearcher struct {
+	search.Backend
+	origin Origin
+}
+
+func (s *originSearcher) Search(ctx context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
+	projects, err := s.Backend.Search(ctx, query, numDesiredResults)
+
+	for i, p := range projects {
+		projects[i] = &originProject{Backend: p, origin: s.origin}
+	}
+
+	return projects, err
+}
+
+// originProject is a project that was found by the search backend of origin.
+type originProject struct {
+	project.Backend
+	origin Origin
+}
+
+// Metadata returns the metadata of the project, which embedding does not expose.
+func (p *originProject) Metadata() *project.Metadata {
+	return project.MetadataOf(p.Backend)
+}
+
+// originOf returns the origin of p, or nil if it is not known.
+func originOf(p project.Backend) *Origin {
+	if o, ok := p.(*originProject); ok {
+		origin := o.origin
+		return &origin
+	}
+
+	return nil
+}
+
+// retrievers are the retrieval backends of the origins of the projects of a
+// manifest.
+type retrievers struct {
+	defaultOrigin Origin
+	backends      map[string]*retry.Backend
+}
+
+// newRetrievers returns the retrieval backend of each origin of the projects of m.
+// Projects without an origin, e.g., of manifests written by older versions, are
+// retrieved from the corpus of c, if it has one, and cloned with Git otherwise.
+func newRetrievers(ctx context.Context, c *Contents, m *Manifest) (*retrievers, error) {
+	r := &retrievers{
+		defaultOrigin: newOrigin("github", nil),
+		backends:      make(map[string]*retry.Backend),
+	}
+
+	if len(c.Corpus) != 0 {
+		r.defaultOrigin = newOrigin("corpus", map[string]string{"archive": c.Corpus, "manifest": c.CorpusManifest})
+	}
+
+	for _, p := range m.Projects {
+		o := r.origin(&p)
+		if _, ok := r.backends[o.key()]; ok {
+			continue
+		}
+
+		b, err := newRetriever(ctx, c, o)
+		if err != nil {
+			return nil, err
+		}
+		r.backends[o.key()] = b
+	}
+
+	return r, nil
+}
+
+func (r *retrievers) origin(p *Project) Origin {
+	if p.Origin == nil {
+		return r.defaultOrigin
+	}
+
+	return *p.Origin
+}
+
+// get returns the retrieval backend of p.
+func (r *retrievers) get(p *Project) (*retry.Backend, error) {
+	o := r.origin(p)
+
+	b, ok := r.backends[o.key()]
+	if !ok {
+		return nil, fmt.Errorf("no retrieval backend for search backend '%s'", o.Backend)
+	}
+
+	return b, nil
+}
+
+// newRetriever returns the retrieval backend of c for the projects of origin o.
+func newRetriever(ctx context.Context, c *Contents, o Origin) (*retry.Backend, error) {
+	var backend retrieval.Backend
+	var err error
+
+	switch o.Backend {
+	case "github":
+		backend, err = git.Factory(ctx, gitRetrievalConfig(c))
+	case "corpus":
+		backend, err = corpusretrieval.Factory(ctx, &retrieval.BackendConfig{Config: o.Config})
+	case "goproxy":
+		backend, err = goproxyretrieval.Factory(ctx, goproxyRetrievalConfig(c, o))
+	case "local":
+		backend, err = localretrieval.Factory(ctx, &retrieval.BackendConfig{
+			Config: map[string]string{"mode": c.LocalMode},
+		})
+	default:
+		return nil, fmt.Errorf("unsupported search backend '%s'", o.Backend)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("error creating project retriever for search backend '%s': %+v", o.Backend, err)
+	}
+
+	retriever, err := retry.Wrap(ctx, backend, &retrieval.BackendConfig{
+		Config: map[string]string{"max_attempts": strconv.Itoa(c.MaxRetrievalAttempts)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating retrying project retriever: %+v", err)
+	}
+
+	return retriever, nil
+}
+
+// goproxyRetrievalConfig returns the config of the Go module proxy retrieval
+// backend of c for the projects of origin o.
+func goproxyRetrievalConfig(c *Contents, o Origin) *retrieval.BackendConfig {
+	config := map[string]string{
+		"sumdb":    c.GoSumDB,
+		"sum_file": c.GoSumFile,
+	}
+
+	for k, v := range o.Config {
+		config[k] = v
+	}
+
+	return &retrieval.BackendConfig{Config: config}
+}
+
+// gitRetrievalConfig returns the config of the Git retrieval backend of c.
+func gitRetrievalConfig(c *Contents) *retrieval.BackendConfig {
+	retrievalConfig := retrieval.BackendConfig{
+		AuthMethod:  "credentials",
+		Credentials: auth.DefaultChain(c.AuthTokenFile, credentialHosts(c.GitHubBaseURL)...),
+		Config: map[string]string{
+			"submodules":      strconv.FormatBool(c.Submodules),
+			"submodule_depth": strconv.Itoa(c.SubmoduleDepth),
+			"lfs":             strconv.FormatBool(c.LFS),
+			"max_files":       strconv.FormatInt(c.MaxFiles, 10),
+			"max_bytes":       strconv.FormatInt(c.MaxBytes, 10),
+		},
+	}
+
+	if len(c.AuthToken) != 0 {
+		retrievalConfig.AuthMethod = "token"
+		// a single token is enough for cloning, which does not count towards the API quota.
+		retrievalConfig.Config["token"] = strings.TrimSpace(strings.Split(c.AuthToken, ",")[0])
+	}
+
+	if len(c.GitHubAppID) != 0 {
+		retrievalConfig.AuthMethod = "github_app"
+		for k, v := range githubAppConfig(c.GitHubAppID, c.GitHubInstallationID, c.GitHubPrivateKeyFile) {
+			retrievalConfig.Config[k] = v
+		}
+
+		if len(c.GitHubBaseURL) != 0 {
+			retrievalConfig.Config["base_url"] = enterpriseAPIURL(c.GitHubBaseURL)
+		}
+	}
+
+	return &retrievalConfig
+}
+
+// newRunner returns the run backend that executes the command of c.
+func newRunner(ctx context.Context, c *Contents) (run.Backend, error) {
+	cmd, err := binary.Factory(ctx, &run.BackendConfig{
+		Cmd:    c.Command,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to handle command: %+v", err)
+	}
+
+	return cmd, nil
+}
+
+// retrieveProject retrieves p into its directory of root, unless it exceeds
+// limits, and records the status of the retrieval in p.
+func retrieveProject(ctx context.Context, r *retrievers, limits retrieval.Limits, root string, p *Project) {
+	start := time.Now()
+	p.Dir = filepath.FromSlash(p.ID.Dir())
+
+	if p.Metadata != nil {
+		if err := limits.CheckSize(p.Metadata.Size); err != nil {
+			glog.Warningf("skipping project ('%s'): %+v", p.Name, err)
+			p.Retrieval = newStatus(start, err)
+			p.Retrieval.Skipped = true
+			return
+		}
+	}
+
+	dir := filepath.Join(root, p.Dir)
+
+	// a previous attempt, e.g., of an interrupted run, may have left a partial
+	// retrieval behind.
+	if err := os.RemoveAll(dir); err != nil {
+		p.Retrieval = newStatus(start, err)
+		glog.Errorf("error removing previous retrieval of project ('%s'): %+v", p.Name, err)
+		return
+	}
+
+	retriever, err := r.get(p)
+	if err != nil {
+		p.Retrieval = newStatus(start, err)
+		glog.Errorf("error retrieving project ('%s'): %+v", p.Name, err)
+		return
+	}
+
+	attempts, err := retriever.RetrieveWithAttempts(ctx, p.Source, dir)
+	p.Retrieval = newStatus(start, err)
+	p.Retrieval.Attempts = attempts
+
+	if err != nil {
+		var limitErr *retrieval.LimitError
+		if errors.As(err, &limitErr) {
+			p.Retrieval.Skipped = true
+			p.Retrieval.Error = limitErr.Error()
+		}
+
+		glog.Errorf("error retrieving project ('%s'): %+v", p.Name, err)
+		return
+	}
+	glog.Infof("retrieved project '%s' after %d attempt(s)", p.Name, attempts)
+
+	p.Version = headVersion(dir, p.Version)
+}
+
+// retrieveProjects retrieves each project of m into root. m is journaled in j,
+// followed by the status of each project as soon as it is known.
+//
+// If resume, projects that m records as retrieved are not retrieved again, as long
+// as their directory still exists. Failed projects are retried.
+func retrieveProjects(ctx context.Context, j *journal, r *retrievers, limits retrieval.Limits, root string, m *Manifest, resume bool) error {
+	if !resume {
+		for i := range m.Projects {
+			m.Projects[i].Retrieval, m.Projects[i].Run = nil, nil
+		}
+	}
+
+	// the statuses that are resumed are journaled with the projects, so that they
+	// are not lost if the resumed run is interrupted too.
+	if err := j.search(m); err != nil {
+		return err
+	}
+
+	for i := range m.Projects {
+		p := &m.Projects[i]
+
+		// only what is done is journaled, which would otherwise override the status
+		// that is resumed.
+		if resume && retrieved(root, p) {
+			continue
+		}
+
+		p.Run = nil
+		retrieveProject(ctx, r, limits, root, p)
+
+		if err := j.retrieval(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runProjects runs cmd on each project of m that was retrieved into root and
+// journals the status of each run in j. If resume, projects that m records as run
+// successfully are not run again.
+func runProjects(ctx context.Context, j *journal, cmd run.Backend, root string, m *Manifest, resume bool) error {
+	for i := range m.Projects {
+		p := &m.Projects[i]
+		if !retrieved(root, p) || (resume && p.Run != nil && p.Run.OK) {
+			continue
+		}
+
+		runProject(ctx, cmd, root, p)
+
+		if err := j.run(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ran returns whether a command was run on any of the projects of m.
+func ran(m *Manifest) bool {
+	for _, p := range m.Projects {
+		if p.Run != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// retrieved returns whether p was retrieved into its directory of root, which
+// still exists.
+func retrieved(root string, p *Project) bool {
+	if p.Retrieval == nil || !p.Retrieval.OK || len(p.Dir) == 0 {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(root, p.Dir))
+	return err == nil
+}
+
+// runProject runs cmd in the directory of p in root and records the status of the
+// run in p.
+func runProject(ctx context.Context, cmd run.Backend, root string, p *Project) {
+	start := time.Now()
+	dir := filepath.Join(root, p.Dir)
+
+	err := cmd.Run(ctx, dir)
+	if err != nil {
+		glog.Errorf("failed to run binary command in '%s': %+v", dir, err)
+	}
+
+	p.Run = newStatus(start, err)
+}
+
+// runExperiment searches for the projects of c, retrieves them into the projects
+// directory of c, exports them, if c has an export, and runs the command of c, if
+// it has one, on each of them. The status of each project is journaled in the
+// projects directory. If resume, the projects of the journal are used instead of
+// searching again (see retrieveProjects and runProjects).
+func runExperiment(ctx context.Context, c *Contents, resume bool) error {
+	if err := os.MkdirAll(c.ProjectsDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create project directory: %+v", err)
+	}
+
+	root, err := filepath.Abs(c.ProjectsDir)
+	if err != nil {
+		return err
+	}
+
+	m, err := resumeOrSearch(ctx, c, root, resume)
+	if err != nil {
+		return err
+	}
+
+	retriever, err := newRetrievers(ctx, c, m)
+	if err != nil {
+		return err
+	}
+
+	var cmd run.Backend
+	if len(c.Command) != 0 {
+		cmd, err = newRunner(ctx, c)
+		if err != nil {
+			return err
+		}
+	}
+
+	j, err := openJournal(root)
+	if err != nil {
+		return err
+	}
+	defer j.Close()
+
+	limits := retrieval.Limits{MaxSize: c.MaxRepoSize}
+	if err := retrieveProjects(ctx, j, retriever, limits, root, m, resume); err != nil {
+		return err
+	}
+
+	reportSkipped(os.Stderr, m)
+
+	// projects are exported before the command can modify them. A resumed run whose
+	// command already ran was exported before it was interrupted.
+	if len(c.Export) != 0 && !ran(m) {
+		if err := exportProjects(c.Export, root, m); err != nil {
+			return err
+		}
+	}
+
+	if cmd == nil {
+		return nil
+	}
+
+	return runProjects(ctx, j, cmd, root, m, resume)
+}
+
+// resumeOrSearch returns the projects of the journal of root, if resume and it
+// has any, so that a resumed run processes the same projects as the run that it
+// resumes. Otherwise, it searches for the projects of c.
+func resumeOrSearch(ctx context.Context, c *Contents, root string, resume bool) (*Manifest, error) {
+	if resume {
+		m, err := readState(root)
+		if err != nil {
+			return nil, err
+		}
+
+		if m != nil {
+			glog.Infof("resuming %d project(s) from '%s'", len(m.Projects), filepath.Join(root, journalFile))
+			return m, nil
+		}
+	}
+
+	return searchProjects(ctx, c)
+}
+
+// exportProjects exports the retrieved projects of m in root to the archive at p.
+// The projects must not have been modified by running a command on them, so that
+// the archive reproduces the retrieved versions.
+func exportProjects(p string, root string, m *Manifest) error {
+	export := &corpus.Manifest{NeighborVersion: version, Query: m.Query, Sample: m.Sample}
+
+	for _, proj := range m.Projects {
+		if proj.Retrieval == nil || !proj.Retrieval.OK {
+			continue
+		}
+
+		if proj.Run != nil {
+			return fmt.Errorf("failed to export projects to '%s': the command was run on project ('%s')", p, proj.Name)
+		}
+
+		e, err := corpus.NewEntry(proj.Name, proj.Source, proj.Version, proj.Retrieval.StartedAt, filepath.Join(root, proj.Dir))
+		if err != nil {
+			glog.Errorf("failed to add project ('%s') to export: %+v", proj.Name, err)
+			continue
+		}
+		e.Metadata = proj.Metadata
+
+		export.Projects = append(export.Projects, e)
+	}
+
+	if err := corpus.WriteFile(p, export); err != nil {
+		return fmt.Errorf("failed to export projects to '%s': %+v", p, err)
+	}
+	glog.Infof("exported %d project(s) to '%s'", len(export.Projects), p)
+
+	return nil
+}
+
+// searchFactories are the search backends that the queries of a composite search
+// can use. The projects that each of them finds are retrieved by the retrieval
+// backend of the same name (see newRetriever), except for GitHub projects, which
+// are cloned with Git.
+var searchFactories = map[string]search.Factory{
+	"github":  github.Factory,
+	"corpus":  corpussearch.Factory,
+	"goproxy": goproxysearch.Factory,
+	"local":   localsearch.Factory,
+}
+
+// searchMethod returns the search method of a search type.
+func searchMethod(searchType string) (search.Method, error) {
+	switch searchType {
+	case "project", "projects":
+		return search.Project, nil
+	case "code":
+		return search.Code, nil
+	case "version":
+		return search.Version, nil
+	}
+
+	return 0, fmt.Errorf("unsupported search type '%s'", searchType)
+}
+
+// newCompositeSearcher returns a search backend that combines the results of the
+// queries of the config file. Every query shares the authentication of conf and
+// uses searchType unless it specifies its own.
+func newCompositeSearcher(ctx context.Context, conf search.BackendConfig, searchType string, queries []QueryContents) (search.Backend, error) {
+	res := make([]search.Query, 0, len(queries))
+
+	for i, q := range queries {
+		name := q.Backend
+		if len(name) == 0 {
+			name = "github"
+		}
+
+		factory, ok := searchFactories[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported search backend '%s' for query %d", name, i)
+		}
+
+		t := q.SearchType
+		if len(t) == 0 {
+			t = searchType
+		}
+
+		method, err := searchMethod(t)
+		if err != nil {
+			return nil, fmt.Errorf("query %d: %+v", i, err)
+		}
+
+		c := conf
+		c.SearchMethod = method
+		c.Config = make(map[string]string, len(conf.Config)+len(q.Config))
+		for k, v := range conf.Config {
+			c.Config[k] = v
+		}
+		for k, v := range q.Config {
+			c.Config[k] = v
+		}
+
+		b, err := factory(ctx, &c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create search backend for query %d: %+v", i, err)
+		}
+
+		res = append(res, search.Query{
+			Backend:           &originSearcher{Backend: b, origin: newOrigin(name, c.Config)},
+			Query:             q.Query,
+			Operation:         q.Operation,
+			NumDesiredResults: q.NumProjects,
+		})
+	}
+
+	return search.NewComposite(res...)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mccurdyc/neighbor/builtin/project/generic"
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+// mockSearcher is a search backend that finds a project for each source location.
+func intPtr(n int) *int { return &n }
+
+type mockSearcher struct {
+	locations []string
+}
+
+func (m *mockSearcher) Search(ctx context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
+	var res []project.Backend
+	for _, loc := range m.locations {
+		p, err := generic.Factory(ctx, &project.BackendConfig{
+			Name:           loc,
+			SourceLocation: loc,
+			Metadata:       &project.Metadata{Stars: intPtr(1)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+
+	return res, nil
+}
+
+func Test_originSearcher(t *testing.T) {
+	var tests = map[string]struct {
+		input Origin
+		want  *Origin
+	}{
+		"github": {
+			input: newOrigin("github", map[string]string{"token": "secret"}),
+			want:  &Origin{Backend: "github"},
+		},
+
+		"corpus": {
+			input: newOrigin("corpus", map[string]string{"archive": "corpus.tar", "query": "owner/*"}),
+			want:  &Origin{Backend: "corpus", Config: map[string]string{"archive": "corpus.tar"}},
+		},
+
+		"goproxy": {
+			input: newOrigin("goproxy", map[string]string{"proxy": "file:///proxy"}),
+			want:  &Origin{Backend: "goproxy", Config: map[string]string{"proxy": "file:///proxy"}},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			s := &originSearcher{Backend: &mockSearcher{locations: []string{"github.com/owner/repo"}}, origin: tt.input}
+
+			got, err := s.Search(context.TODO(), "", 1)
+			if err != nil {
+				t.Fatalf("Search() unexpected error: %+v", err)
+			}
+
+			p := newProject(got[0])
+
+			if diff := cmp.Diff(tt.want, p.Origin); diff != "" {
+				t.Errorf("Search() origin mismatch (-want +got):\n%s", diff)
+			}
+
+			if p.Metadata == nil || p.Metadata.Stars == nil || *p.Metadata.Stars != 1 {
+				t.Errorf("Search() lost the metadata of the project: %+v", p.Metadata)
+			}
+		})
+	}
+}
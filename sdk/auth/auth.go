@@ -0,0 +1,88 @@
+// Package auth defines the credential providers that search and retrieval
+// backends use to find credentials without them being passed as plaintext
+// command-line arguments or config values.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoCredential is used by providers to indicate that they do not have a
+// credential for a host.
+var ErrNoCredential = fmt.Errorf("no credential found")
+
+// Credential is a username and password or, if Username is empty, a token.
+type Credential struct {
+	Username string
+	Password string
+	// Source describes where the credential was found (e.g., "env:GITHUB_TOKEN").
+	Source string
+}
+
+// Token returns whether the credential is a token rather than a username and
+// password.
+func (c *Credential) Token() bool {
+	return len(c.Username) == 0
+}
+
+// Method returns the auth method and config values that search and retrieval
+// backends expect for the credential, i.e., "token" with a "token" config value
+// or "basic" with "username" and "password" config values.
+func (c *Credential) Method() (string, map[string]string) {
+	if c.Token() {
+		return "token", map[string]string{"token": c.Password}
+	}
+
+	return "basic", map[string]string{"username": c.Username, "password": c.Password}
+}
+
+// Provider is the minimal interface for a source of credentials.
+type Provider interface {
+	// Credential returns the credential for a host (e.g., github.com) or
+	// ErrNoCredential.
+	Credential(ctx context.Context, host string) (*Credential, error)
+}
+
+// Chain is a Provider that returns the credential of the first provider that has
+// one, i.e., providers earlier in the chain take precedence.
+type Chain []Provider
+
+// Credential returns the first credential found for host.
+func (c Chain) Credential(ctx context.Context, host string) (*Credential, error) {
+	for _, p := range c {
+		cred, err := p.Credential(ctx, host)
+		if errors.Is(err, ErrNoCredential) {
+			continue
+		}
+
+		return cred, err
+	}
+
+	return nil, ErrNoCredential
+}
+
+// DefaultChain returns the providers in their default order of precedence:
+//
+//  1. the token in tokenFile, if specified
+//  2. the GITHUB_TOKEN environment variable
+//  3. the netrc file (i.e., $NETRC or ~/.netrc)
+//  4. the Git credential helper (i.e., `git credential fill`)
+//
+// The tokens in tokenFile and GITHUB_TOKEN are only sent to DefaultHosts and
+// hosts (e.g., a GitHub Enterprise Server).
+func DefaultChain(tokenFile string, hosts ...string) Chain {
+	tokenHosts := append(append([]string{}, DefaultHosts...), hosts...)
+
+	var c Chain
+	if len(tokenFile) != 0 {
+		c = append(c, &File{Path: tokenFile, Hosts: tokenHosts})
+	}
+
+	return append(c,
+		&Env{Name: DefaultEnv, Hosts: tokenHosts},
+		&Netrc{},
+		&GitCredential{},
+	)
+}
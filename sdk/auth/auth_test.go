@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type errProvider struct {
+	err error
+}
+
+func (p *errProvider) Credential(ctx context.Context, host string) (*Credential, error) {
+	return nil, p.err
+}
+
+func Test_Chain(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "neighbor-auth")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	tokenFile := filepath.Join(tmp, "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("filetoken\n"), 0600); err != nil {
+		t.Fatalf("failed to write token file: %+v", err)
+	}
+
+	const env = "NEIGHBOR_TEST_TOKEN"
+	os.Setenv(env, "envtoken")
+	defer os.Unsetenv(env)
+
+	type want struct {
+		cred *Credential
+		err  error
+	}
+
+	var tests = map[string]struct {
+		input Chain
+		want  want
+	}{
+		"file_first": {
+			input: Chain{&File{Path: tokenFile}, &Env{Name: env}},
+			want: want{
+				cred: &Credential{Password: "filetoken", Source: "file:" + tokenFile},
+			},
+		},
+
+		"other_host_skipped": {
+			input: Chain{&File{Path: tokenFile, Hosts: []string{"ghe.example.com"}}, &Env{Name: env}},
+			want: want{
+				cred: &Credential{Password: "envtoken", Source: "env:" + env},
+			},
+		},
+
+		"other_host_none": {
+			input: Chain{&File{Path: tokenFile, Hosts: []string{"ghe.example.com"}}, &Env{Name: env, Hosts: []string{"ghe.example.com"}}},
+			want: want{
+				err: ErrNoCredential,
+			},
+		},
+
+		"unset_env_skipped": {
+			input: Chain{&Env{Name: "NEIGHBOR_TEST_UNSET"}, &Env{Name: env}},
+			want: want{
+				cred: &Credential{Password: "envtoken", Source: "env:" + env},
+			},
+		},
+
+		"missing_file": {
+			input: Chain{&File{Path: filepath.Join(tmp, "missing")}, &Env{Name: env}},
+			want: want{
+				err: fmt.Errorf("failed to read token file: open %s: no such file or directory", filepath.Join(tmp, "missing")),
+			},
+		},
+
+		"provider_error": {
+			input: Chain{&errProvider{err: fmt.Errorf("keychain locked")}, &Env{Name: env}},
+			want: want{
+				err: fmt.Errorf("keychain locked"),
+			},
+		},
+
+		"none": {
+			input: Chain{&errProvider{err: ErrNoCredential}},
+			want: want{
+				err: ErrNoCredential,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := tt.input.Credential(context.TODO(), "github.com")
+
+			if diff := cmp.Diff(tt.want.cred, got); diff != "" {
+				t.Errorf("Credential() mismatch (-want +got):\n%s", diff)
+			}
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error() || errors.Is(x, y)
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Credential() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+		})
+	}
+}
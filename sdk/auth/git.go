@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// GitCredential is a Provider that asks the Git credential helpers configured
+// by the user (e.g., a keychain) for credentials, i.e., `git credential fill`.
+// https://git-scm.com/docs/git-credential
+type GitCredential struct {
+	// Git is the path to the git executable (defaults to git on the PATH).
+	Git string
+}
+
+// Credential returns the credential for https://host. Git is never allowed to
+// prompt for credentials.
+func (g *GitCredential) Credential(ctx context.Context, host string) (*Credential, error) {
+	git := g.Git
+	if len(git) == 0 {
+		git = "git"
+	}
+
+	if _, err := exec.LookPath(git); err != nil {
+		return nil, ErrNoCredential
+	}
+
+	cmd := exec.CommandContext(ctx, git, "credential", "fill")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("protocol=https\nhost=%s\n\n", host))
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	// git fails when no helper has a credential and it cannot prompt.
+	if err := cmd.Run(); err != nil {
+		return nil, ErrNoCredential
+	}
+
+	cred := &Credential{Source: "git-credential"}
+
+	s := bufio.NewScanner(&stdout)
+	for s.Scan() {
+		kv := strings.SplitN(s.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "username":
+			cred.Username = kv[1]
+		case "password":
+			cred.Password = kv[1]
+		}
+	}
+
+	if len(cred.Password) == 0 {
+		return nil, ErrNoCredential
+	}
+
+	return cred, nil
+}
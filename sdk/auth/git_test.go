@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_GitCredential(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake git executable is a shell script")
+	}
+
+	tmp, err := ioutil.TempDir("", "neighbor-git-credential")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	// git is a fake git executable that only has credentials for github.com.
+	git := filepath.Join(tmp, "git")
+	script := `#!/bin/sh
+input=$(cat)
+case "$input" in
+*host=github.com*)
+	printf 'protocol=https\nhost=github.com\nusername=octocat\npassword=helpertoken\n'
+	;;
+*)
+	echo "fatal: could not read Username" >&2
+	exit 128
+	;;
+esac
+`
+	if err := ioutil.WriteFile(git, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write fake git: %+v", err)
+	}
+
+	type want struct {
+		cred *Credential
+		err  error
+	}
+
+	var tests = map[string]struct {
+		input string
+		want  want
+	}{
+		"found": {
+			input: "github.com",
+			want: want{
+				cred: &Credential{Username: "octocat", Password: "helpertoken", Source: "git-credential"},
+			},
+		},
+
+		"not_found": {
+			input: "github.example.com",
+			want: want{
+				err: ErrNoCredential,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := (&GitCredential{Git: git}).Credential(context.TODO(), tt.input)
+
+			if gotErr != tt.want.err {
+				t.Errorf("Credential() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if diff := cmp.Diff(tt.want.cred, got); diff != "" {
+				t.Errorf("Credential() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Netrc is a Provider that reads credentials from a netrc file.
+// https://www.gnu.org/software/inetutils/manual/html_node/The-_002enetrc-file.html
+type Netrc struct {
+	// Path is the path to the netrc file (defaults to $NETRC or ~/.netrc).
+	Path string
+}
+
+// Credential returns the login and password of the machine entry for host or of
+// the default entry.
+func (n *Netrc) Credential(ctx context.Context, host string) (*Credential, error) {
+	p := n.Path
+	if len(p) == 0 {
+		p = defaultNetrcPath()
+	}
+
+	b, err := ioutil.ReadFile(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNoCredential
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read netrc file: %+v", err)
+	}
+
+	for _, m := range parseNetrc(string(b)) {
+		if (m.name == host || m.name == "") && len(m.password) != 0 {
+			return &Credential{Username: m.login, Password: m.password, Source: "netrc:" + p}, nil
+		}
+	}
+
+	return nil, ErrNoCredential
+}
+
+func defaultNetrcPath() string {
+	if p := os.Getenv("NETRC"); len(p) != 0 {
+		return p
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+
+	return filepath.Join(home, ".netrc")
+}
+
+// netrcMachine is a machine entry of a netrc file. The default entry has an
+// empty name.
+type netrcMachine struct {
+	name     string
+	login    string
+	password string
+}
+
+// parseNetrc returns the machine entries of a netrc file, with the default entry
+// last. Macro definitions are skipped.
+func parseNetrc(data string) []*netrcMachine {
+	var (
+		machines []*netrcMachine
+		def      *netrcMachine
+		m        *netrcMachine
+	)
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		for j := 0; j < len(fields); j++ {
+			switch fields[j] {
+			case "machine":
+				if j+1 < len(fields) {
+					m = &netrcMachine{name: fields[j+1]}
+					machines = append(machines, m)
+					j++
+				}
+			case "default":
+				def = &netrcMachine{}
+				m = def
+			case "login", "password":
+				if m != nil && j+1 < len(fields) {
+					if fields[j] == "login" {
+						m.login = fields[j+1]
+					} else {
+						m.password = fields[j+1]
+					}
+					j++
+				}
+			case "macdef":
+				// a macro definition ends at the next empty line.
+				for i++; i < len(lines) && len(strings.TrimSpace(lines[i])) != 0; i++ {
+				}
+				j = len(fields)
+			}
+		}
+	}
+
+	if def != nil {
+		machines = append(machines, def)
+	}
+
+	return machines
+}
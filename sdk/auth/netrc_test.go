@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_Netrc(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "neighbor-netrc")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	p := filepath.Join(tmp, ".netrc")
+	netrc := `machine example.com login someone password secret
+
+macdef init
+machine github.com login macro password macro
+
+machine github.com
+	login octocat
+	password ghtoken
+default login anonymous password guest
+`
+	if err := ioutil.WriteFile(p, []byte(netrc), 0600); err != nil {
+		t.Fatalf("failed to write netrc file: %+v", err)
+	}
+
+	type want struct {
+		cred *Credential
+		err  error
+	}
+
+	var tests = map[string]struct {
+		input string
+		want  want
+	}{
+		"machine": {
+			input: "github.com",
+			want: want{
+				cred: &Credential{Username: "octocat", Password: "ghtoken", Source: "netrc:" + p},
+			},
+		},
+
+		"default": {
+			input: "github.example.com",
+			want: want{
+				cred: &Credential{Username: "anonymous", Password: "guest", Source: "netrc:" + p},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := (&Netrc{Path: p}).Credential(context.TODO(), tt.input)
+
+			if gotErr != tt.want.err {
+				t.Errorf("Credential() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if diff := cmp.Diff(tt.want.cred, got); diff != "" {
+				t.Errorf("Credential() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := (&Netrc{Path: filepath.Join(tmp, "missing")}).Credential(context.TODO(), "github.com"); err != ErrNoCredential {
+		t.Errorf("Credential() missing file \n\tgotErr: '%+v'\n\twantErr: '%+v'", err, ErrNoCredential)
+	}
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultEnv is the environment variable read by Env when a name is not specified.
+const DefaultEnv = "GITHUB_TOKEN"
+
+// DefaultHosts are the hosts that Env and File send their token to when hosts
+// are not specified.
+var DefaultHosts = []string{"github.com", "api.github.com"}
+
+// forHost returns whether host is one of hosts, which default to DefaultHosts.
+func forHost(hosts []string, host string) bool {
+	if len(hosts) == 0 {
+		hosts = DefaultHosts
+	}
+
+	for _, h := range hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Env is a Provider that reads a token from an environment variable.
+type Env struct {
+	// Name is the name of the environment variable (defaults to DefaultEnv).
+	Name string
+	// Hosts are the hosts that the token is for (defaults to DefaultHosts).
+	Hosts []string
+}
+
+// Credential returns the token in the environment variable if host is one of
+// the hosts of e.
+func (e *Env) Credential(ctx context.Context, host string) (*Credential, error) {
+	if !forHost(e.Hosts, host) {
+		return nil, ErrNoCredential
+	}
+
+	name := e.Name
+	if len(name) == 0 {
+		name = DefaultEnv
+	}
+
+	token := strings.TrimSpace(os.Getenv(name))
+	if len(token) == 0 {
+		return nil, ErrNoCredential
+	}
+
+	return &Credential{Password: token, Source: "env:" + name}, nil
+}
+
+// File is a Provider that reads a token from a file, e.g., a mounted secret.
+type File struct {
+	Path string
+	// Hosts are the hosts that the token is for (defaults to DefaultHosts).
+	Hosts []string
+}
+
+// Credential returns the token in the file if host is one of the hosts of f. A
+// missing file is an error because the file was explicitly specified.
+func (f *File) Credential(ctx context.Context, host string) (*Credential, error) {
+	if !forHost(f.Hosts, host) {
+		return nil, ErrNoCredential
+	}
+
+	b, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %+v", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if len(token) == 0 {
+		return nil, fmt.Errorf("token file '%s' is empty", f.Path)
+	}
+
+	return &Credential{Password: token, Source: "file:" + f.Path}, nil
+}
@@ -0,0 +1,40 @@
+package corpus
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// HashDir returns the h1: hash (i.e., the hash used by go.sum files) of the
+// contents of the project at dir, excluding the .git directory. The hash of a
+// symlink is the hash of its target path.
+func HashDir(dir string) (string, error) {
+	files, err := localFiles(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return dirhash.Hash1(files, func(name string) (io.ReadCloser, error) {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		info, err := os.Lstat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(p)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(strings.NewReader(target)), nil
+		}
+
+		return os.Open(p)
+	})
+}
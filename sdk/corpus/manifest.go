@@ -0,0 +1,174 @@
+// Package corpus defines the archive format used to export and import the
+// projects retrieved by a run of neighbor.
+//
+// An archive is a tar file, optionally compressed, that contains a manifest.json
+// file followed by the files of every project under projects/<dir>/, where dir is
+// the directory of the canonical ID of the project (see project.ID.Dir). Archives
+// are deterministic, i.e., entries are sorted and timestamps, owners and
+// permissions are normalized so that identical inputs result in byte-identical
+// archives.
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/sample"
+)
+
+// ManifestName is the name of the manifest file in an archive.
+const ManifestName = "manifest.json"
+
+// projectsDir is the directory in an archive that contains the projects.
+const projectsDir = "projects"
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	// NeighborVersion is the version of neighbor that created the archive.
+	NeighborVersion string `json:"neighbor_version"`
+	// Query is the search query used to find the projects.
+	Query string `json:"query"`
+	// Sample describes how the projects were sampled from the search results, if
+	// they were sampled.
+	Sample *sample.Options `json:"sample,omitempty"`
+	// Projects are the projects in the archive, sorted by path.
+	Projects []Entry `json:"projects"`
+}
+
+// Entry describes a single project in an archive.
+type Entry struct {
+	// Name is the name of the project.
+	Name string `json:"name"`
+	// Source is where the project was retrieved from.
+	Source string `json:"source"`
+	// Version is the exact version (e.g., Git commit SHA) that was retrieved.
+	Version string `json:"version"`
+	// RetrievedAt is when the project was retrieved.
+	RetrievedAt time.Time `json:"retrieved_at"`
+	// Path is the directory of the project in the archive.
+	Path string `json:"path"`
+	// Hash is the h1: hash of the contents of the project (see HashDir).
+	Hash string `json:"hash"`
+	// Metadata is the metadata of the project as reported by the source it was
+	// found at, if any.
+	Metadata *project.Metadata `json:"metadata,omitempty"`
+
+	// LocalLocation is where the project can be found on disk when creating an
+	// archive. It is not part of the manifest.
+	LocalLocation string `json:"-"`
+}
+
+// NewEntry is a constructor that returns an Entry for a project retrieved to dir.
+// The archive path is derived from the canonical ID of the project (see
+// project.ID.Dir), so that projects with the same name from different hosts or
+// owners do not collide.
+func NewEntry(name string, source string, version string, retrievedAt time.Time, dir string) (Entry, error) {
+	if len(name) == 0 {
+		return Entry{}, fmt.Errorf("project name cannot be empty")
+	}
+
+	loc := source
+	if len(loc) == 0 {
+		loc = name
+	}
+
+	return Entry{
+		Name:          name,
+		Source:        source,
+		Version:       version,
+		RetrievedAt:   retrievedAt.UTC(),
+		Path:          entryPath(project.ParseID(loc, version)),
+		LocalLocation: dir,
+	}, nil
+}
+
+// entryPath returns the archive path of the project identified by id, which never
+// escapes the projects directory.
+func entryPath(id project.ID) string {
+	return path.Join(projectsDir, id.Dir())
+}
+
+// ReadManifest decodes a manifest.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %+v", err)
+	}
+
+	for _, e := range m.Projects {
+		if !strings.HasPrefix(e.Path, projectsDir+"/") || path.Clean(e.Path) != e.Path {
+			return nil, fmt.Errorf("invalid project path '%s' in manifest", e.Path)
+		}
+	}
+
+	return &m, nil
+}
+
+// Entry returns the entry of the project found at src, which is the source of the
+// entry. Entries without a source, and entries whose name is src and not shared
+// with another entry, are also found by their name.
+func (m *Manifest) Entry(src string) (Entry, bool) {
+	var named []Entry
+	for _, e := range m.Projects {
+		if e.Source == src || (len(e.Source) == 0 && e.Name == src) {
+			return e, true
+		}
+
+		if e.Name == src {
+			named = append(named, e)
+		}
+	}
+
+	if len(named) != 1 {
+		return Entry{}, false
+	}
+
+	return named[0], true
+}
+
+func (m *Manifest) sort() {
+	sort.Slice(m.Projects, func(i, j int) bool {
+		return m.Projects[i].Path < m.Projects[j].Path
+	})
+}
+
+// localFiles returns the files of a project on disk, relative to dir and sorted,
+// excluding the .git directory.
+func localFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,215 @@
+package corpus
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrHashMismatch is used to indicate that the contents of an extracted project
+// do not match the hash recorded in the manifest.
+var ErrHashMismatch = fmt.Errorf("hash mismatch")
+
+// ReadFileManifest returns the manifest of the archive at p.
+func ReadFileManifest(p string) (*Manifest, error) {
+	var m *Manifest
+
+	err := walkFile(p, func(tr *tar.Reader, hdr *tar.Header) (bool, error) {
+		if hdr.Name != ManifestName {
+			return false, fmt.Errorf("archive does not start with %s", ManifestName)
+		}
+
+		var err error
+		m, err = ReadManifest(tr)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if m == nil {
+		return nil, fmt.Errorf("archive does not contain %s", ManifestName)
+	}
+
+	return m, nil
+}
+
+// LoadManifest returns the manifest at manifest or, if manifest is empty, the
+// manifest of the archive at archive.
+func LoadManifest(archive string, manifest string) (*Manifest, error) {
+	if len(manifest) == 0 {
+		return ReadFileManifest(archive)
+	}
+
+	f, err := os.Open(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest: %+v", err)
+	}
+	defer f.Close()
+
+	return ReadManifest(f)
+}
+
+// ExtractFile extracts the project described by e from the archive at p to dir
+// and verifies its contents against the hash in e. dir is removed if the contents
+// do not match.
+//
+// The archive is read sequentially, i.e., every call decompresses the archive up
+// to the end of the project.
+func ExtractFile(p string, e Entry, dir string) error {
+	if len(e.Hash) == 0 {
+		return fmt.Errorf("missing hash for project '%s'", e.Name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	prefix := e.Path + "/"
+	found := false
+	// symlinks are recorded so that later entries cannot be written through them.
+	symlinks := make(map[string]bool)
+
+	err := walkFile(p, func(tr *tar.Reader, hdr *tar.Header) (bool, error) {
+		if !strings.HasPrefix(hdr.Name, prefix) {
+			// the files of a project are contiguous, so the project is complete once
+			// an entry of another project follows it.
+			return !found, nil
+		}
+		found = true
+
+		name := strings.TrimPrefix(hdr.Name, prefix)
+		if err := checkName(name, symlinks); err != nil {
+			return false, err
+		}
+
+		return true, extract(tr, hdr, filepath.Join(dir, filepath.FromSlash(name)), name, symlinks)
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("archive does not contain project '%s'", e.Name)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	got, err := HashDir(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("failed to hash project '%s': %+v", e.Name, err)
+	}
+
+	if got != e.Hash {
+		os.RemoveAll(dir)
+		return fmt.Errorf("%s: %w\n\textracted: %s\n\tmanifest:  %s", e.Name, ErrHashMismatch, got, e.Hash)
+	}
+
+	return nil
+}
+
+// checkName rejects names that could be written outside of the project directory.
+func checkName(name string, symlinks map[string]bool) error {
+	if len(name) == 0 || path.Clean(name) != name || strings.HasPrefix(name, "../") || path.IsAbs(name) {
+		return fmt.Errorf("invalid entry name '%s' in archive", name)
+	}
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if symlinks[dir] {
+			return fmt.Errorf("invalid entry name '%s' in archive", name)
+		}
+	}
+
+	return nil
+}
+
+func extract(tr *tar.Reader, hdr *tar.Header, p string, name string, symlinks map[string]bool) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeSymlink:
+		symlinks[name] = true
+		return os.Symlink(hdr.Linkname, p)
+	case tar.TypeReg:
+		f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(hdr.Mode)&0755)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(f, tr)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+
+	return fmt.Errorf("unsupported entry type for '%s' in archive", name)
+}
+
+// walkFile calls fn for every entry of the archive at p until fn returns false
+// or an error.
+func walkFile(p string, fn func(*tar.Reader, *tar.Header) (bool, error)) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %+v", err)
+	}
+	defer f.Close()
+
+	r, err := decompress(p, f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %+v", err)
+		}
+
+		more, err := fn(tr, hdr)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (r zstdReadCloser) Close() error {
+	r.Decoder.Close()
+	return nil
+}
+
+// decompress returns a reader that decompresses according to the extension of p.
+func decompress(p string, r io.Reader) (io.ReadCloser, error) {
+	switch {
+	case strings.HasSuffix(p, ".tar"):
+		return ioutil.NopCloser(r), nil
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		return gzip.NewReader(r)
+	case strings.HasSuffix(p, ".tar.zst"):
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zstdReadCloser{d}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported archive extension for '%s' (must be .tar, .tar.gz, .tgz or .tar.zst)", p)
+}
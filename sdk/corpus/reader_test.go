@@ -0,0 +1,169 @@
+package corpus
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_ExtractFile(t *testing.T) {
+	files := map[string]string{
+		"main.go":    "package main\n",
+		"pkg/lib.go": "package pkg\n",
+	}
+
+	src := newProject(t, files, time.Unix(0, 0))
+	defer os.RemoveAll(src)
+
+	tmp, err := ioutil.TempDir("", "neighbor-corpus-extract")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	e, err := NewEntry("owner/name", "https://github.com/owner/name", "abc", time.Now(), src)
+	if err != nil {
+		t.Fatalf("failed to create entry: %+v", err)
+	}
+
+	archive := filepath.Join(tmp, "corpus.tar.zst")
+	if err := WriteFile(archive, &Manifest{Projects: []Entry{e}}); err != nil {
+		t.Fatalf("failed to write archive: %+v", err)
+	}
+
+	m, err := ReadFileManifest(archive)
+	if err != nil {
+		t.Fatalf("ReadFileManifest() unexpected error: %+v", err)
+	}
+
+	e, ok := m.Entry("owner/name")
+	if !ok {
+		t.Fatalf("ReadFileManifest() missing entry 'owner/name'")
+	}
+
+	type input struct {
+		entry Entry
+	}
+
+	type want struct {
+		files    map[string]string
+		mismatch bool
+		err      bool
+	}
+
+	tampered := e
+	tampered.Hash = "h1:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="
+
+	missing := e
+	missing.Name, missing.Path = "owner/other", "projects/owner/other"
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"extracted": {
+			input: input{entry: e},
+			want:  want{files: files},
+		},
+
+		"hash_mismatch": {
+			input: input{entry: tampered},
+			want:  want{mismatch: true, err: true},
+		},
+
+		"missing_project": {
+			input: input{entry: missing},
+			want:  want{err: true},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(tmp, name)
+			gotErr := ExtractFile(archive, tt.input.entry, dir)
+
+			if (gotErr != nil) != tt.want.err {
+				t.Fatalf("ExtractFile() unexpected error: %+v", gotErr)
+			}
+
+			if errors.Is(gotErr, ErrHashMismatch) != tt.want.mismatch {
+				t.Errorf("ExtractFile() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, ErrHashMismatch)
+			}
+
+			if tt.want.err {
+				if _, err := os.Stat(dir); !os.IsNotExist(err) {
+					t.Errorf("ExtractFile() did not clean up '%s'", dir)
+				}
+				return
+			}
+
+			for name, contents := range tt.want.files {
+				got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+				if err != nil || string(got) != contents {
+					t.Errorf("ExtractFile() mismatched contents of '%s': '%s' (%+v)", name, got, err)
+				}
+			}
+		})
+	}
+}
+
+func Test_Manifest_Entry(t *testing.T) {
+	m := &Manifest{
+		Projects: []Entry{
+			{Name: "owner/name", Source: "https://github.com/owner/name.git", Path: "projects/github.com/owner/name"},
+			{Name: "owner/name", Source: "https://gitlab.com/owner/name.git", Path: "projects/gitlab.com/owner/name"},
+			{Name: "owner/other", Source: "https://github.com/owner/other.git", Path: "projects/github.com/owner/other"},
+			{Name: "local", Path: "projects/_/local"},
+		},
+	}
+
+	type want struct {
+		path string
+		ok   bool
+	}
+
+	var tests = map[string]struct {
+		input string
+		want  want
+	}{
+		"source": {
+			input: "https://gitlab.com/owner/name.git",
+			want:  want{path: "projects/gitlab.com/owner/name", ok: true},
+		},
+
+		"unique_name": {
+			input: "owner/other",
+			want:  want{path: "projects/github.com/owner/other", ok: true},
+		},
+
+		"ambiguous_name": {
+			input: "owner/name",
+			want:  want{},
+		},
+
+		"no_source": {
+			input: "local",
+			want:  want{path: "projects/_/local", ok: true},
+		},
+
+		"not_found": {
+			input: "owner/missing",
+			want:  want{},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, ok := m.Entry(tt.input)
+
+			if ok != tt.want.ok || got.Path != tt.want.path {
+				t.Errorf("Entry() \n\tgot: '%+v', %t\n\twant: '%+v', %t", got.Path, ok, tt.want.path, tt.want.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package corpus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// epoch is the modification time of every entry in an archive.
+var epoch = time.Unix(0, 0).UTC()
+
+// WriteFile writes an archive of the projects in m to the file at p. The
+// compression is chosen by the extension of p, i.e., .tar, .tar.gz, .tgz or .tar.zst.
+func WriteFile(p string, m *Manifest) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".corpus-*")
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %+v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	cw, err := compress(p, tmp)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = Write(cw, m)
+	if cerr := cw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p)
+}
+
+// Write writes an uncompressed archive of the projects in m to w. The hash of
+// every project is computed from its local location and recorded in m.
+func Write(w io.Writer, m *Manifest) error {
+	for i := range m.Projects {
+		e := &m.Projects[i]
+
+		if len(e.LocalLocation) == 0 {
+			return fmt.Errorf("missing local location for project '%s'", e.Name)
+		}
+
+		h, err := HashDir(e.LocalLocation)
+		if err != nil {
+			return fmt.Errorf("failed to hash project '%s': %+v", e.Name, err)
+		}
+		e.Hash = h
+	}
+	m.sort()
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	if err := writeFile(tw, &tar.Header{Name: ManifestName, Mode: 0644}, bytes.NewReader(b), int64(len(b))); err != nil {
+		return err
+	}
+
+	for _, e := range m.Projects {
+		if err := writeProject(tw, e); err != nil {
+			return fmt.Errorf("failed to write project '%s': %+v", e.Name, err)
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeProject(tw *tar.Writer, e Entry) error {
+	files, err := localFiles(e.LocalLocation)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range files {
+		p := filepath.Join(e.LocalLocation, filepath.FromSlash(name))
+
+		info, err := os.Lstat(p)
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{Name: e.Path + "/" + name}
+
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Mode = 0777
+			if hdr.Linkname, err = os.Readlink(p); err != nil {
+				return err
+			}
+
+			if err := writeFile(tw, hdr, nil, 0); err != nil {
+				return err
+			}
+		case info.Mode().IsRegular():
+			hdr.Mode = 0644
+			if info.Mode()&0111 != 0 {
+				hdr.Mode = 0755
+			}
+
+			if err := writeLocalFile(tw, hdr, p, info.Size()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeLocalFile(tw *tar.Writer, hdr *tar.Header, p string, size int64) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return writeFile(tw, hdr, f, size)
+}
+
+// writeFile writes a normalized entry to tw.
+func writeFile(tw *tar.Writer, hdr *tar.Header, r io.Reader, size int64) error {
+	if hdr.Typeflag == 0 {
+		hdr.Typeflag = tar.TypeReg
+	}
+	hdr.Size = size
+	hdr.ModTime = epoch
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+
+	if r == nil {
+		return nil
+	}
+
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// compress returns a writer that compresses according to the extension of p.
+func compress(p string, w io.Writer) (io.WriteCloser, error) {
+	switch {
+	case strings.HasSuffix(p, ".tar"):
+		return nopWriteCloser{w}, nil
+	case strings.HasSuffix(p, ".tar.gz"), strings.HasSuffix(p, ".tgz"):
+		// the zero gzip header contains neither a name nor a modification time.
+		return gzip.NewWriter(w), nil
+	case strings.HasSuffix(p, ".tar.zst"):
+		// a single encoder goroutine guarantees deterministic output.
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+
+	return nil, fmt.Errorf("unsupported archive extension for '%s' (must be .tar, .tar.gz, .tgz or .tar.zst)", p)
+}
@@ -0,0 +1,183 @@
+package corpus
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newProject writes files to a temporary directory, setting every modification
+// time to mtime.
+func newProject(t *testing.T, files map[string]string, mtime time.Time) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "neighbor-corpus")
+	if err != nil {
+		t.Fatalf("failed to create directory: %+v", err)
+	}
+
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("failed to create directory: %+v", err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(contents), 0600); err != nil {
+			t.Fatalf("failed to write file: %+v", err)
+		}
+
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatalf("failed to set modification time: %+v", err)
+		}
+	}
+
+	return dir
+}
+
+func Test_WriteFile_deterministic(t *testing.T) {
+	files := map[string]string{
+		"main.go":         "package main\n",
+		"pkg/lib.go":      "package pkg\n",
+		".git/HEAD":       "ref: refs/heads/master\n",
+		"docs/README.md":  "# docs\n",
+		"testdata/a.json": "{}\n",
+	}
+
+	retrievedAt := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// archive writes the same projects, created at different times, to an
+	// archive with the specified name.
+	archive := func(t *testing.T, name string, mtime time.Time) []byte {
+		t.Helper()
+
+		a := newProject(t, files, mtime)
+		defer os.RemoveAll(a)
+		b := newProject(t, files, mtime)
+		defer os.RemoveAll(b)
+
+		ea, err := NewEntry("owner/a", "https://github.com/owner/a", "abc", retrievedAt, a)
+		if err != nil {
+			t.Fatalf("failed to create entry: %+v", err)
+		}
+
+		eb, err := NewEntry("owner/b", "https://github.com/owner/b", "def", retrievedAt, b)
+		if err != nil {
+			t.Fatalf("failed to create entry: %+v", err)
+		}
+
+		tmp, err := ioutil.TempDir("", "neighbor-corpus-archive")
+		if err != nil {
+			t.Fatalf("failed to create directory: %+v", err)
+		}
+		defer os.RemoveAll(tmp)
+
+		p := filepath.Join(tmp, name)
+		m := &Manifest{NeighborVersion: "test", Query: "language:go", Projects: []Entry{eb, ea}}
+		if err := WriteFile(p, m); err != nil {
+			t.Fatalf("WriteFile() unexpected error: %+v", err)
+		}
+
+		got, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatalf("failed to read archive: %+v", err)
+		}
+
+		return got
+	}
+
+	for _, name := range []string{"corpus.tar", "corpus.tar.gz", "corpus.tgz", "corpus.tar.zst"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			first := archive(t, name, time.Unix(1000, 0))
+			second := archive(t, name, time.Unix(2000, 0))
+
+			if !bytes.Equal(first, second) {
+				t.Errorf("WriteFile() archives of identical inputs differ")
+			}
+		})
+	}
+}
+
+func Test_WriteFile_unsupported(t *testing.T) {
+	want := fmt.Errorf("unsupported archive extension for 'corpus.zip' (must be .tar, .tar.gz, .tgz or .tar.zst)")
+
+	gotErr := WriteFile("corpus.zip", &Manifest{})
+
+	if gotErr == nil || gotErr.Error() != want.Error() {
+		t.Errorf("WriteFile() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, want)
+	}
+
+	if _, err := os.Stat("corpus.zip"); !os.IsNotExist(err) {
+		t.Errorf("WriteFile() created 'corpus.zip'")
+	}
+}
+
+func Test_NewEntry(t *testing.T) {
+	type input struct {
+		name    string
+		source  string
+		version string
+	}
+
+	type want struct {
+		path string
+		err  error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"source": {
+			input: input{name: "owner/name", source: "https://github.com/owner/name.git", version: "abc"},
+			want:  want{path: "projects/github.com/owner/name@abc"},
+		},
+
+		"same_name_different_host": {
+			input: input{name: "owner/name", source: "https://gitlab.com/owner/name.git", version: "abc"},
+			want:  want{path: "projects/gitlab.com/owner/name@abc"},
+		},
+
+		"no_source": {
+			input: input{name: "owner/name"},
+			want:  want{path: "projects/_/owner/name"},
+		},
+
+		"traversal": {
+			input: input{name: "../name"},
+			want:  want{path: "projects/_/%2e%2e/name"},
+		},
+
+		"empty": {
+			input: input{},
+			want:  want{err: fmt.Errorf("project name cannot be empty")},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := NewEntry(tt.input.name, tt.input.source, tt.input.version, time.Time{}, "")
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("NewEntry() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if got.Path != tt.want.path {
+				t.Errorf("NewEntry() mismatched path: \n\tgot: '%+v'\n\twant: '%+v'", got.Path, tt.want.path)
+			}
+		})
+	}
+}
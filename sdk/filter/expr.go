@@ -0,0 +1,682 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+// SyntaxError is returned by Parse for an invalid expression.
+type SyntaxError struct {
+	// Pos is the byte offset in the expression at which the error occurred.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses a filter expression into a predicate.
+//
+// An expression compares the fields of a project with literals and combines the
+// comparisons with !, && and || (in order of precedence) and parentheses. The
+// fields are
+//
+//	name, version, source                     strings
+//	language, license, default_branch, parent strings
+//	stars, size                               numbers
+//	archived, fork, fork_of_selected          booleans
+//	topics, duplicates                        lists of strings
+//	created_at, pushed_at                     times
+//	attributes.<key>                          strings
+//
+// Literals are numbers (50), strings ('mit' or "mit"), booleans (true, false),
+// durations (90d, 2w, 12h) and lists of strings (['mit', 'apache-2.0']).
+//
+// Numbers and times are compared with ==, !=, <, <=, > and >=, where a time is
+// compared with a date string (e.g., created_at > '2019-01-01'). Strings and
+// booleans are compared with == and !=. Strings are compared case-insensitively.
+// "x in list" is true if the string x is in a list literal or list field and
+// "t within d" is true if the time t is within the duration d of now. Attributes
+// compared with numbers are converted to numbers. A boolean field by itself, e.g.,
+// !fork, is a predicate.
+//
+// Stars and times that the source of a project does not report are unknown and
+// every comparison with an unknown field, including !=, is false.
+// fork_of_selected is true for forks of another one of the projects that are
+// selected from, e.g., !fork_of_selected excludes forks of projects in the set.
+func Parse(expr string) (Selector, error) {
+	toks, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &parser{toks: toks}
+
+	n, err := ps.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := ps.peek(); t.kind != tokEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected '%s'", t.text)}
+	}
+
+	if n.kind != kindBool {
+		return nil, &SyntaxError{Pos: n.pos, Msg: fmt.Sprintf("expression is a %s, not a boolean", n.kind)}
+	}
+
+	return func(projects []project.Backend) Predicate {
+		notForkOf := NotForkOf(projects)
+
+		return func(p project.Backend) bool {
+			return n.eval(&subject{p: p, m: project.MetadataOf(p), notForkOf: notForkOf}).(bool)
+		}
+	}, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokDuration
+	tokString
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators are sorted so that longer operators are matched first.
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "<", ">", "(", ")", "[", "]", ","}
+
+func lex(expr string) ([]token, error) {
+	var toks []token
+
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(expr) && rune(expr[j]) != c; j++ {
+				if expr[j] == '\\' && j+1 < len(expr) {
+					j++
+				}
+				b.WriteByte(expr[j])
+			}
+
+			if j >= len(expr) {
+				return nil, &SyntaxError{Pos: i, Msg: "unterminated string"}
+			}
+
+			toks = append(toks, token{kind: tokString, text: b.String(), pos: i})
+			i = j + 1
+
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(expr) && (unicode.IsDigit(rune(expr[j])) || expr[j] == '.') {
+				j++
+			}
+
+			kind := tokNumber
+			if j < len(expr) && unicode.IsLetter(rune(expr[j])) {
+				kind = tokDuration
+				for j < len(expr) && unicode.IsLetter(rune(expr[j])) {
+					j++
+				}
+			}
+
+			toks = append(toks, token{kind: kind, text: expr[i:j], pos: i})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(expr) && isIdent(rune(expr[j])) {
+				j++
+			}
+
+			toks = append(toks, token{kind: tokIdent, text: expr[i:j], pos: i})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+
+			if len(op) == 0 {
+				return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character '%c'", c)}
+			}
+
+			toks = append(toks, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+
+	return append(toks, token{kind: tokEOF, text: "end of filter", pos: len(expr)}), nil
+}
+
+func isIdent(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.'
+}
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindNumber
+	kindString
+	kindTime
+	kindDuration
+	kindList
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindBool:
+		return "boolean"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindTime:
+		return "time"
+	case kindDuration:
+		return "duration"
+	case kindList:
+		return "list"
+	}
+
+	return "unknown"
+}
+
+// subject is a project that an expression is evaluated against.
+type subject struct {
+	p project.Backend
+	m *project.Metadata
+	// notForkOf is the NotForkOf predicate of the projects that p is selected from.
+	notForkOf Predicate
+}
+
+// node is a type-checked expression.
+type node struct {
+	kind kind
+	pos  int
+	eval func(s *subject) interface{}
+	// literal is the value of the node if it is a literal.
+	literal interface{}
+	// attribute is whether the node is an attribute, which are converted to numbers
+	// when compared with numbers.
+	attribute bool
+}
+
+var fields = map[string]struct {
+	kind kind
+	get  func(s *subject) interface{}
+}{
+	"name":             {kindString, func(s *subject) interface{} { return s.p.Name() }},
+	"version":          {kindString, func(s *subject) interface{} { return s.p.Version() }},
+	"source":           {kindString, func(s *subject) interface{} { return s.p.SourceLocation() }},
+	"language":         {kindString, func(s *subject) interface{} { return s.m.Language }},
+	"license":          {kindString, func(s *subject) interface{} { return s.m.License }},
+	"default_branch":   {kindString, func(s *subject) interface{} { return s.m.DefaultBranch }},
+	"parent":           {kindString, func(s *subject) interface{} { return s.m.Parent }},
+	"stars":            {kindNumber, func(s *subject) interface{} { return numberOf(s.m.Stars) }},
+	"size":             {kindNumber, func(s *subject) interface{} { return float64(s.m.Size) }},
+	"archived":         {kindBool, func(s *subject) interface{} { return s.m.Archived }},
+	"fork":             {kindBool, func(s *subject) interface{} { return s.m.Fork }},
+	"fork_of_selected": {kindBool, func(s *subject) interface{} { return !s.notForkOf(s.p) }},
+	"topics":           {kindList, func(s *subject) interface{} { return s.m.Topics }},
+	"duplicates":       {kindList, func(s *subject) interface{} { return s.m.Duplicates }},
+	"created_at":       {kindTime, func(s *subject) interface{} { return timeOf(s.m.CreatedAt) }},
+	"pushed_at":        {kindTime, func(s *subject) interface{} { return timeOf(s.m.PushedAt) }},
+}
+
+// timeOf returns the time of t, or nil if it is not known, which makes every
+// comparison with it false.
+func timeOf(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+
+	return *t
+}
+
+// numberOf returns n as a number, or NaN if it is not known, which makes every
+// comparison with it false.
+func numberOf(n *int) float64 {
+	if n == nil {
+		return math.NaN()
+	}
+
+	return float64(*n)
+}
+
+// attributePrefix is the prefix of the fields that refer to metadata attributes.
+const attributePrefix = "attributes."
+
+type parser struct {
+	toks []token
+	i    int
+}
+
+func (ps *parser) peek() token {
+	return ps.toks[ps.i]
+}
+
+func (ps *parser) next() token {
+	t := ps.toks[ps.i]
+	if t.kind != tokEOF {
+		ps.i++
+	}
+
+	return t
+}
+
+func (ps *parser) isOp(op string) bool {
+	t := ps.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+func (ps *parser) expect(op string) error {
+	if t := ps.next(); t.kind != tokOp || t.text != op {
+		return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("expected '%s', found '%s'", op, t.text)}
+	}
+
+	return nil
+}
+
+func (ps *parser) parseOr() (*node, error) {
+	return ps.parseBinary("||", ps.parseAnd, true)
+}
+
+func (ps *parser) parseAnd() (*node, error) {
+	return ps.parseBinary("&&", ps.parseUnary, false)
+}
+
+// parseBinary parses a sequence of operands separated by op. Evaluation stops at
+// the first operand that evaluates to short, which is the result of the sequence.
+func (ps *parser) parseBinary(op string, operand func() (*node, error), short bool) (*node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for ps.isOp(op) {
+		ps.next()
+
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range []*node{left, right} {
+			if n.kind != kindBool {
+				return nil, &SyntaxError{Pos: n.pos, Msg: fmt.Sprintf("operand of '%s' is a %s, not a boolean", op, n.kind)}
+			}
+		}
+
+		l, r := left, right
+		left = &node{kind: kindBool, pos: l.pos, eval: func(s *subject) interface{} {
+			if v := l.eval(s).(bool); v == short {
+				return v
+			}
+
+			return r.eval(s)
+		}}
+	}
+
+	return left, nil
+}
+
+func (ps *parser) parseUnary() (*node, error) {
+	if !ps.isOp("!") {
+		return ps.parseComparison()
+	}
+
+	t := ps.next()
+
+	n, err := ps.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	if n.kind != kindBool {
+		return nil, &SyntaxError{Pos: n.pos, Msg: fmt.Sprintf("operand of '!' is a %s, not a boolean", n.kind)}
+	}
+
+	return &node{kind: kindBool, pos: t.pos, eval: func(s *subject) interface{} {
+		return !n.eval(s).(bool)
+	}}, nil
+}
+
+var comparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (ps *parser) parseComparison() (*node, error) {
+	left, err := ps.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := ps.peek()
+
+	switch {
+	case t.kind == tokOp && comparisons[t.text]:
+		ps.next()
+
+		right, err := ps.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		return compare(t, left, right)
+
+	case t.kind == tokIdent && t.text == "in":
+		ps.next()
+
+		right, err := ps.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		if left.kind != kindString || right.kind != kindList {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("cannot use 'in' with a %s and a %s", left.kind, right.kind)}
+		}
+
+		return &node{kind: kindBool, pos: left.pos, eval: func(s *subject) interface{} {
+			return containsFold(right.eval(s).([]string), left.eval(s).(string))
+		}}, nil
+
+	case t.kind == tokIdent && t.text == "within":
+		ps.next()
+
+		right, err := ps.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+
+		if left.kind != kindTime || right.kind != kindDuration {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("cannot use 'within' with a %s and a %s", left.kind, right.kind)}
+		}
+
+		return &node{kind: kindBool, pos: left.pos, eval: func(s *subject) interface{} {
+			t, ok := left.eval(s).(time.Time)
+			return ok && within(t, right.eval(s).(time.Duration))
+		}}, nil
+	}
+
+	return left, nil
+}
+
+func (ps *parser) parsePrimary() (*node, error) {
+	t := ps.next()
+
+	switch t.kind {
+	case tokOp:
+		switch t.text {
+		case "(":
+			n, err := ps.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := ps.expect(")"); err != nil {
+				return nil, err
+			}
+
+			return n, nil
+
+		case "[":
+			return ps.parseList(t)
+		}
+
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("invalid number '%s'", t.text)}
+		}
+
+		return literal(kindNumber, t.pos, v), nil
+
+	case tokDuration:
+		v, err := parseDuration(t.text)
+		if err != nil {
+			return nil, &SyntaxError{Pos: t.pos, Msg: err.Error()}
+		}
+
+		return literal(kindDuration, t.pos, v), nil
+
+	case tokString:
+		return literal(kindString, t.pos, t.text), nil
+
+	case tokIdent:
+		switch t.text {
+		case "true", "false":
+			return literal(kindBool, t.pos, t.text == "true"), nil
+		}
+
+		if strings.HasPrefix(t.text, attributePrefix) && len(t.text) > len(attributePrefix) {
+			key := strings.TrimPrefix(t.text, attributePrefix)
+			return &node{kind: kindString, pos: t.pos, attribute: true, eval: func(s *subject) interface{} {
+				return s.m.Attributes[key]
+			}}, nil
+		}
+
+		f, ok := fields[t.text]
+		if !ok {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unknown field '%s'", t.text)}
+		}
+
+		return &node{kind: f.kind, pos: t.pos, eval: f.get}, nil
+	}
+
+	return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("unexpected '%s'", t.text)}
+}
+
+func (ps *parser) parseList(open token) (*node, error) {
+	var values []string
+
+	for !ps.isOp("]") {
+		if len(values) > 0 {
+			if err := ps.expect(","); err != nil {
+				return nil, err
+			}
+		}
+
+		t := ps.next()
+		if t.kind != tokString {
+			return nil, &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf("lists may only contain strings, found '%s'", t.text)}
+		}
+
+		values = append(values, t.text)
+	}
+	ps.next()
+
+	return literal(kindList, open.pos, values), nil
+}
+
+func literal(k kind, pos int, v interface{}) *node {
+	return &node{kind: k, pos: pos, literal: v, eval: func(*subject) interface{} { return v }}
+}
+
+// dateLayouts are the layouts of the date strings that times are compared with.
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+func compare(op token, left *node, right *node) (*node, error) {
+	// a date string compared with a time is parsed once, up front.
+	for _, n := range []*node{left, right} {
+		other := left
+		if n == left {
+			other = right
+		}
+
+		if other.kind == kindTime && n.kind == kindString && n.literal != nil {
+			t, err := parseDate(n.literal.(string))
+			if err != nil {
+				return nil, &SyntaxError{Pos: n.pos, Msg: err.Error()}
+			}
+
+			*n = *literal(kindTime, n.pos, t)
+		}
+
+		if other.kind == kindNumber && n.attribute {
+			get := n.eval
+			*n = node{kind: kindNumber, pos: n.pos, eval: func(s *subject) interface{} {
+				v, err := strconv.ParseFloat(get(s).(string), 64)
+				if err != nil {
+					return math.NaN()
+				}
+
+				return v
+			}}
+		}
+	}
+
+	if left.kind != right.kind {
+		return nil, &SyntaxError{Pos: op.pos, Msg: fmt.Sprintf("cannot compare a %s with a %s", left.kind, right.kind)}
+	}
+
+	if left.kind == kindList || left.kind == kindDuration {
+		return nil, &SyntaxError{Pos: op.pos, Msg: fmt.Sprintf("cannot compare a %s", left.kind)}
+	}
+
+	ordered := left.kind == kindNumber || left.kind == kindTime
+	if !ordered && op.text != "==" && op.text != "!=" {
+		return nil, &SyntaxError{Pos: op.pos, Msg: fmt.Sprintf("cannot use '%s' with a %s", op.text, left.kind)}
+	}
+
+	return &node{kind: kindBool, pos: left.pos, eval: func(s *subject) interface{} {
+		c, ok := cmpValues(left.eval(s), right.eval(s))
+		if !ok {
+			return false
+		}
+
+		switch op.text {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		}
+
+		return c >= 0
+	}}, nil
+}
+
+// cmpValues compares two values of the same kind. It returns false if the values
+// are not comparable, e.g., NaN or an unknown time.
+func cmpValues(l, r interface{}) (int, bool) {
+	switch l := l.(type) {
+	case bool:
+		if l == r.(bool) {
+			return 0, true
+		}
+		return 1, true
+
+	case string:
+		if strings.EqualFold(l, r.(string)) {
+			return 0, true
+		}
+		return 1, true
+
+	case float64:
+		r := r.(float64)
+		if math.IsNaN(l) || math.IsNaN(r) {
+			return 0, false
+		}
+
+		switch {
+		case l < r:
+			return -1, true
+		case l > r:
+			return 1, true
+		}
+		return 0, true
+
+	case time.Time:
+		r, ok := r.(time.Time)
+		if !ok {
+			return 0, false
+		}
+
+		switch {
+		case l.Before(r):
+			return -1, true
+		case l.After(r):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date '%s', expected YYYY-MM-DD or RFC 3339", s)
+}
+
+// parseDuration parses a duration, which in addition to the units supported by
+// time.ParseDuration may be in days (d) or weeks (w).
+func parseDuration(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+
+	for u, d := range units {
+		if !strings.HasSuffix(s, u) {
+			continue
+		}
+
+		n, err := strconv.ParseFloat(strings.TrimSuffix(s, u), 64)
+		if err != nil {
+			break
+		}
+
+		return time.Duration(n * float64(d)), nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+
+	return d, nil
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
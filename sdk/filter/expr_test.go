@@ -0,0 +1,175 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+func Test_Parse(t *testing.T) {
+	now = func() time.Time { return time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	projects := []project.Backend{
+		&mockProject{name: "owner/a", metadata: &project.Metadata{
+			Stars:      intPtr(100),
+			Language:   "Go",
+			License:    "mit",
+			Topics:     []string{"cli", "git"},
+			CreatedAt:  timePtr(time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)),
+			PushedAt:   timePtr(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)),
+			Attributes: map[string]string{"forks_count": "12"},
+		}},
+		&mockProject{name: "owner/b", metadata: &project.Metadata{
+			Stars:     intPtr(60),
+			Language:  "Rust",
+			License:   "apache-2.0",
+			Fork:      true,
+			CreatedAt: timePtr(time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+		&mockProject{name: "owner/c", metadata: &project.Metadata{
+			Stars:    intPtr(10),
+			Language: "Go",
+			License:  "gpl-3.0",
+			Archived: true,
+		}},
+		// the stars and times of owner/d are not known.
+		&mockProject{name: "owner/d", metadata: &project.Metadata{
+			Fork:   true,
+			Parent: "OWNER/A",
+		}},
+	}
+
+	type want struct {
+		names []string
+		err   error
+	}
+
+	var tests = map[string]struct {
+		input string
+		want  want
+	}{
+		"request_example": {
+			input: "!fork && stars > 50 && license in ['mit','apache-2.0']",
+			want:  want{names: []string{"owner/a"}},
+		},
+
+		"or_precedence": {
+			input: "archived || fork && stars >= 60",
+			want:  want{names: []string{"owner/b", "owner/c"}},
+		},
+
+		"parentheses": {
+			input: "(archived || fork) && stars >= 60",
+			want:  want{names: []string{"owner/b"}},
+		},
+
+		"case_insensitive_string": {
+			input: `language == "go" && name != 'OWNER/C'`,
+			want:  want{names: []string{"owner/a"}},
+		},
+
+		"topics": {
+			input: "'cli' in topics",
+			want:  want{names: []string{"owner/a"}},
+		},
+
+		"within": {
+			input: "pushed_at within 90d",
+			want:  want{names: []string{"owner/a"}},
+		},
+
+		"date": {
+			input: "created_at >= '2019-01-01'",
+			want:  want{names: []string{"owner/b"}},
+		},
+
+		"numeric_attribute": {
+			input: "attributes.forks_count > 10",
+			want:  want{names: []string{"owner/a"}},
+		},
+
+		"unknown_stars": {
+			input: "stars < 1000 || stars != 10",
+			want:  want{names: []string{"owner/a", "owner/b", "owner/c"}},
+		},
+
+		"unknown_time": {
+			input: "pushed_at < '2021-01-01' || created_at != '2018-01-01'",
+			want:  want{names: []string{"owner/a", "owner/b"}},
+		},
+
+		"unknown_time_within": {
+			input: "!(pushed_at within 90d)",
+			want:  want{names: []string{"owner/b", "owner/c", "owner/d"}},
+		},
+
+		"fork_of_selected": {
+			input: "fork && !fork_of_selected",
+			want:  want{names: []string{"owner/b"}},
+		},
+
+		"unknown_field": {
+			input: "stars > 1 && color == 'red'",
+			want:  want{err: fmt.Errorf("invalid filter at position 13: unknown field 'color'")},
+		},
+
+		"type_mismatch": {
+			input: "stars > 'many'",
+			want:  want{err: fmt.Errorf("invalid filter at position 6: cannot compare a number with a string")},
+		},
+
+		"not_boolean": {
+			input: "stars",
+			want:  want{err: fmt.Errorf("invalid filter at position 0: expression is a number, not a boolean")},
+		},
+
+		"unterminated_string": {
+			input: "license == 'mit",
+			want:  want{err: fmt.Errorf("invalid filter at position 11: unterminated string")},
+		},
+
+		"missing_parenthesis": {
+			input: "(fork",
+			want:  want{err: fmt.Errorf("invalid filter at position 5: expected ')', found 'end of filter'")},
+		},
+
+		"invalid_duration": {
+			input: "pushed_at within 3x",
+			want:  want{err: fmt.Errorf("invalid filter at position 17: invalid duration '3x'")},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			sel, gotErr := Parse(tt.input)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("Parse() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			got := names(Select(projects, sel))
+
+			if diff := cmp.Diff(tt.want.names, got); diff != "" {
+				t.Errorf("Parse() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
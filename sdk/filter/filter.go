@@ -0,0 +1,143 @@
+// Package filter provides composable predicates over projects that are used to
+// filter the results of a search before the projects are retrieved.
+//
+// Predicates can be composed in Go with And, Or and Not or parsed from an
+// expression into a Selector (see Parse), e.g.,
+//
+//	!fork && stars > 50 && license in ['mit', 'apache-2.0']
+package filter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+// Predicate reports whether a project should be kept.
+type Predicate func(p project.Backend) bool
+
+// Selector returns the predicate that projects are kept by when they are
+// selected from projects, for predicates that depend on the other projects in the
+// set (e.g., NotForkOf).
+type Selector func(projects []project.Backend) Predicate
+
+// Apply returns the projects for which pred is true, preserving their order. A
+// nil pred keeps every project.
+func Apply(projects []project.Backend, pred Predicate) []project.Backend {
+	if pred == nil {
+		return projects
+	}
+
+	res := make([]project.Backend, 0, len(projects))
+	for _, p := range projects {
+		if pred(p) {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// Select returns the projects that are kept by the predicate that sel returns for
+// projects, preserving their order. A nil sel keeps every project.
+func Select(projects []project.Backend, sel Selector) []project.Backend {
+	if sel == nil {
+		return projects
+	}
+
+	return Apply(projects, sel(projects))
+}
+
+// And returns a predicate that is true if all preds are true.
+func And(preds ...Predicate) Predicate {
+	return func(p project.Backend) bool {
+		for _, pred := range preds {
+			if !pred(p) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Or returns a predicate that is true if any of preds is true.
+func Or(preds ...Predicate) Predicate {
+	return func(p project.Backend) bool {
+		for _, pred := range preds {
+			if pred(p) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not returns a predicate that negates pred.
+func Not(pred Predicate) Predicate {
+	return func(p project.Backend) bool {
+		return !pred(p)
+	}
+}
+
+// Fork is true for projects that are forks of another project.
+func Fork() Predicate {
+	return func(p project.Backend) bool {
+		return project.MetadataOf(p).Fork
+	}
+}
+
+// MinStars is true for projects with at least n stars. It is false for projects
+// whose number of stars is not known.
+func MinStars(n int) Predicate {
+	return func(p project.Backend) bool {
+		stars := project.MetadataOf(p).Stars
+		return stars != nil && *stars >= n
+	}
+}
+
+// LicenseIn is true for projects with one of the specified licenses. Licenses are
+// compared case-insensitively.
+func LicenseIn(licenses ...string) Predicate {
+	return func(p project.Backend) bool {
+		return containsFold(licenses, project.MetadataOf(p).License)
+	}
+}
+
+// PushedWithin is true for projects that were pushed to within d of now. It is
+// false for projects whose push time is not known.
+func PushedWithin(d time.Duration) Predicate {
+	return func(p project.Backend) bool {
+		pushed := project.MetadataOf(p).PushedAt
+		return pushed != nil && within(*pushed, d)
+	}
+}
+
+// NotForkOf is true for projects that are not forks of any of projects, e.g., to
+// exclude the forks of projects that are already in a result set. Names are
+// compared case-insensitively.
+func NotForkOf(projects []project.Backend) Predicate {
+	names := make(map[string]struct{}, len(projects))
+	for _, p := range projects {
+		names[strings.ToLower(p.Name())] = struct{}{}
+	}
+
+	return func(p project.Backend) bool {
+		m := project.MetadataOf(p)
+		if !m.Fork || len(m.Parent) == 0 {
+			return true
+		}
+
+		_, ok := names[strings.ToLower(m.Parent)]
+		return !ok
+	}
+}
+
+// now is the current time. It is a variable so that it can be overridden in tests.
+var now = time.Now
+
+func within(t time.Time, d time.Duration) bool {
+	return now().Sub(t) <= d
+}
@@ -0,0 +1,98 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/src-d/go-billy.v4"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+type mockProject struct {
+	name     string
+	metadata *project.Metadata
+}
+
+func (m *mockProject) Name() string                                   { return m.name }
+func (m *mockProject) Version() string                                { return "version" }
+func (m *mockProject) RetrievalFunc() retrieval.Backend               { return nil }
+func (m *mockProject) SourceLocation() string                         { return "source/" + m.name }
+func (m *mockProject) LocalLocation() string                          { return "" }
+func (m *mockProject) SetLocalLocation(string) project.Backend        { return m }
+func (m *mockProject) Filesystem() billy.Filesystem                   { return nil }
+func (m *mockProject) SetFilesystem(billy.Filesystem) project.Backend { return m }
+func (m *mockProject) Metadata() *project.Metadata                    { return m.metadata.Clone() }
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func intPtr(n int) *int { return &n }
+
+func names(projects []project.Backend) []string {
+	res := make([]string, 0, len(projects))
+	for _, p := range projects {
+		res = append(res, p.Name())
+	}
+
+	return res
+}
+
+func Test_Apply(t *testing.T) {
+	now = func() time.Time { return time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	projects := []project.Backend{
+		&mockProject{name: "upstream/a", metadata: &project.Metadata{Stars: intPtr(100), License: "mit", PushedAt: timePtr(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC))}},
+		&mockProject{name: "fork/a", metadata: &project.Metadata{Stars: intPtr(1), License: "MIT", Fork: true, Parent: "upstream/a"}},
+		&mockProject{name: "fork/b", metadata: &project.Metadata{Stars: intPtr(60), License: "gpl-3.0", Fork: true, Parent: "upstream/b"}},
+		&mockProject{name: "nometadata"},
+	}
+
+	var tests = map[string]struct {
+		input Predicate
+		want  []string
+	}{
+		"nil": {
+			input: nil,
+			want:  []string{"upstream/a", "fork/a", "fork/b", "nometadata"},
+		},
+
+		"not_fork": {
+			input: Not(Fork()),
+			want:  []string{"upstream/a", "nometadata"},
+		},
+
+		"and": {
+			input: And(MinStars(50), LicenseIn("mit", "apache-2.0")),
+			want:  []string{"upstream/a"},
+		},
+
+		"or": {
+			input: Or(MinStars(50), LicenseIn("mit")),
+			want:  []string{"upstream/a", "fork/a", "fork/b"},
+		},
+
+		"pushed_within": {
+			input: PushedWithin(90 * 24 * time.Hour),
+			want:  []string{"upstream/a"},
+		},
+
+		"not_fork_of": {
+			input: NotForkOf(projects),
+			want:  []string{"upstream/a", "fork/b", "nometadata"},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := names(Apply(projects, tt.input))
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Apply() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,8 @@ package project
 import (
 	"context"
 
+	"gopkg.in/src-d/go-billy.v4"
+
 	"github.com/mccurdyc/neighbor/sdk/retrieval"
 )
 
@@ -17,6 +19,10 @@ type Backend interface {
 	SourceLocation() string
 	LocalLocation() string
 	SetLocalLocation(string) Backend
+	// Filesystem returns the in-memory filesystem of a project that was retrieved
+	// without a local location (i.e., directory on disk), or nil.
+	Filesystem() billy.Filesystem
+	SetFilesystem(billy.Filesystem) Backend
 }
 
 // BackendConfig is the configuration parameters for a project backend.
@@ -37,6 +43,9 @@ type BackendConfig struct {
 	// I think it is the latter because Search returns a project, but does not
 	// retrieve the project.
 	RetrievalFunc retrieval.Backend
+	// Metadata describes the project as reported by the source it was found at,
+	// if known.
+	Metadata *Metadata
 
 	// Config is a way to set additional, optional and/or secondary configuration values.
 	Config map[string]string
@@ -0,0 +1,184 @@
+package project
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ID is the canonical identity of a project. Projects with the same host,
+// namespace and name are the same project, regardless of the search backend that
+// found them or the form of their source location. The host is case-insensitive,
+// as with Go module paths, and so are the namespace and name of projects hosted
+// at case-insensitive hosts (e.g., github.com).
+type ID struct {
+	// Host is the host that the project is hosted at (e.g., github.com), if known.
+	Host string `json:"host,omitempty"`
+	// Namespace is the owner or group, possibly nested, of the project.
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the project.
+	Name string `json:"name"`
+	// Version is the version of the project, if known.
+	Version string `json:"version,omitempty"`
+}
+
+// IDOf returns the ID of a project, which is derived from its source location (or
+// name, if it does not have one) and version.
+func IDOf(p Backend) ID {
+	loc := p.SourceLocation()
+	if len(loc) == 0 {
+		loc = p.Name()
+	}
+
+	return ParseID(loc, p.Version())
+}
+
+// ParseID returns the ID of a project found at loc. loc is a url (e.g.,
+// https://github.com/owner/repo.git or file:///src/repo), an scp-like Git url (e.g.,
+// git@github.com:owner/repo.git) or a path (e.g., github.com/owner/repo or
+// owner/repo) that is optionally followed by @version. The first element of a
+// path is the host if it contains a dot, as with Go module paths. An empty version
+// defaults to the version of loc.
+func ParseID(loc string, version string) ID {
+	var host, p string
+
+	if u, err := url.Parse(loc); err == nil && len(u.Host) != 0 {
+		host, p = u.Host, u.Path
+	} else if err == nil && u.Scheme == "file" {
+		// local repositories, e.g., file:///src/repo, do not have a host.
+		p = u.Path
+	} else if i := strings.Index(loc, "@"); i >= 0 && strings.Contains(loc[i:], ":") && !strings.Contains(loc[:i], "/") {
+		// scp-like Git urls, e.g., git@github.com:owner/repo.git
+		host, p = splitHost(strings.Replace(loc[i+1:], ":", "/", 1))
+	} else {
+		if i := strings.LastIndex(loc, "@"); i >= 0 {
+			if len(version) == 0 {
+				version = loc[i+1:]
+			}
+			loc = loc[:i]
+		}
+
+		host, p = splitHost(loc)
+		if !strings.Contains(host, ".") || strings.Trim(host, ".") != host {
+			host, p = "", loc
+		}
+	}
+
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	namespace, name := path.Split(p)
+
+	return ID{
+		Host:      strings.ToLower(host),
+		Namespace: strings.Trim(namespace, "/"),
+		Name:      name,
+		Version:   version,
+	}
+}
+
+func splitHost(p string) (string, string) {
+	p = strings.TrimPrefix(p, "/")
+
+	i := strings.Index(p, "/")
+	if i < 0 {
+		return p, ""
+	}
+
+	return p[:i], p[i:]
+}
+
+// caseInsensitiveHosts are the hosts that ignore the case of namespaces and
+// names, e.g., github.com/Owner/Repo is github.com/owner/repo.
+var caseInsensitiveHosts = map[string]bool{
+	"github.com": true,
+}
+
+// Key identifies a project, regardless of its version. The keys of projects
+// hosted at case-insensitive hosts are lowercased.
+func (id ID) Key() string {
+	if caseInsensitiveHosts[id.Host] {
+		return strings.ToLower(id.path())
+	}
+
+	return id.path()
+}
+
+// String returns the ID as host/namespace/name@version.
+func (id ID) String() string {
+	s := id.path()
+	if len(id.Version) != 0 {
+		s = fmt.Sprintf("%s@%s", s, id.Version)
+	}
+
+	return s
+}
+
+// path returns host/namespace/name, omitting empty elements.
+func (id ID) path() string {
+	var elems []string
+	for _, e := range []string{id.Host, id.Namespace, id.Name} {
+		if len(e) != 0 {
+			elems = append(elems, e)
+		}
+	}
+
+	return strings.Join(elems, "/")
+}
+
+// Dir returns a relative, slash-separated directory for the project, i.e.,
+// host/namespace/name@version. Every element is escaped so that the directory of
+// different projects never collides, even on case-insensitive file systems, and
+// never escapes the directory that it is joined with. Projects without a host are in the _
+// directory, which is not a valid host.
+func (id ID) Dir() string {
+	host := id.Host
+	if len(host) == 0 {
+		host = "_"
+	} else {
+		host = escape(host)
+	}
+
+	elems := []string{host}
+	if len(id.Namespace) != 0 {
+		for _, e := range strings.Split(id.Namespace, "/") {
+			elems = append(elems, escape(e))
+		}
+	}
+
+	name := escape(id.Name)
+	if len(id.Version) != 0 {
+		name = fmt.Sprintf("%s@%s", name, escape(id.Version))
+	}
+
+	return path.Join(append(elems, name)...)
+}
+
+// escape replaces every upper-case letter of s with an exclamation mark followed
+// by the lower-case letter, as the module proxy protocol does, and percent-encodes
+// every other byte other than a-z, 0-9, '-', '_' and '.', as well as the elements
+// "", "." and "..".
+func escape(s string) string {
+	switch s {
+	case "":
+		return "%"
+	case ".":
+		return "%2e"
+	case "..":
+		return "%2e%2e"
+	}
+
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z':
+			b.WriteByte('!')
+			b.WriteByte(c + 'a' - 'A')
+		case 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02x", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_ParseID(t *testing.T) {
+	type input struct {
+		loc     string
+		version string
+	}
+
+	type want struct {
+		id  ID
+		key string
+		dir string
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"clone_url": {
+			input: input{loc: "https://github.com/Owner/Repo.git", version: "abc123"},
+			want: want{
+				id:  ID{Host: "github.com", Namespace: "Owner", Name: "Repo", Version: "abc123"},
+				key: "github.com/owner/repo",
+				dir: "github.com/!owner/!repo@abc123",
+			},
+		},
+
+		"file_url": {
+			input: input{loc: "file:///src/Repo", version: "abc123"},
+			want: want{
+				id:  ID{Namespace: "src", Name: "Repo", Version: "abc123"},
+				key: "src/Repo",
+				dir: "_/src/!repo@abc123",
+			},
+		},
+
+		"scp_url": {
+			input: input{loc: "git@gitlab.com:group/subgroup/repo.git"},
+			want: want{
+				id:  ID{Host: "gitlab.com", Namespace: "group/subgroup", Name: "repo"},
+				key: "gitlab.com/group/subgroup/repo",
+				dir: "gitlab.com/group/subgroup/repo",
+			},
+		},
+
+		"module_version": {
+			input: input{loc: "github.com/pkg/errors@v0.9.1+incompatible"},
+			want: want{
+				id:  ID{Host: "github.com", Namespace: "pkg", Name: "errors", Version: "v0.9.1+incompatible"},
+				key: "github.com/pkg/errors",
+				dir: "github.com/pkg/errors@v0.9.1%2bincompatible",
+			},
+		},
+
+		"name_without_host": {
+			input: input{loc: "owner/repo", version: "v1"},
+			want: want{
+				id:  ID{Namespace: "owner", Name: "repo", Version: "v1"},
+				key: "owner/repo",
+				dir: "_/owner/repo@v1",
+			},
+		},
+
+		"traversal": {
+			input: input{loc: "../../etc/passwd"},
+			want: want{
+				id:  ID{Namespace: "../../etc", Name: "passwd"},
+				key: "../../etc/passwd",
+				dir: "_/%2e%2e/%2e%2e/etc/passwd",
+			},
+		},
+
+		"case_sensitive": {
+			input: input{loc: "https://GitHub.com/foo/x"},
+			want: want{
+				id:  ID{Host: "github.com", Namespace: "foo", Name: "x"},
+				key: "github.com/foo/x",
+				dir: "github.com/foo/x",
+			},
+		},
+
+		"case_sensitive_host": {
+			input: input{loc: "https://example.com/Foo/X"},
+			want: want{
+				id:  ID{Host: "example.com", Namespace: "Foo", Name: "X"},
+				key: "example.com/Foo/X",
+				dir: "example.com/!foo/!x",
+			},
+		},
+
+		"escaped_exclamation_mark": {
+			input: input{loc: "example.com/!foo/x"},
+			want: want{
+				id:  ID{Host: "example.com", Namespace: "!foo", Name: "x"},
+				key: "example.com/!foo/x",
+				dir: "example.com/%21foo/x",
+			},
+		},
+
+		"unsafe_characters": {
+			input: input{loc: "https://example.com/a%20b/c%25d"},
+			want: want{
+				id:  ID{Host: "example.com", Namespace: "a b", Name: "c%d"},
+				key: "example.com/a b/c%d",
+				dir: "example.com/a%20b/c%25d",
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := ParseID(tt.input.loc, tt.input.version)
+
+			if diff := cmp.Diff(tt.want.id, got); diff != "" {
+				t.Errorf("ParseID() mismatch (-want +got):\n%s", diff)
+			}
+
+			if got.Key() != tt.want.key {
+				t.Errorf("Key(): \n\tgot: '%+v'\n\twant: '%+v'", got.Key(), tt.want.key)
+			}
+
+			if got.Dir() != tt.want.dir {
+				t.Errorf("Dir(): \n\tgot: '%+v'\n\twant: '%+v'", got.Dir(), tt.want.dir)
+			}
+		})
+	}
+}
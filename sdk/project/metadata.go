@@ -0,0 +1,98 @@
+package project
+
+import (
+	"time"
+)
+
+// Metadata describes a project as reported by the source it was found at (e.g.,
+// the GitHub search API). Fields that the source does not report are left as
+// their zero value. Metadata is serializable to JSON.
+type Metadata struct {
+	// Stars is the number of stars (or similar) of the project, if known.
+	Stars *int `json:"stars,omitempty"`
+	// Language is the primary language of the project.
+	Language string `json:"language,omitempty"`
+	// License is the identifier of the license of the project (e.g., mit, apache-2.0).
+	License string `json:"license,omitempty"`
+	// DefaultBranch is the branch that is retrieved by default.
+	DefaultBranch string `json:"default_branch,omitempty"`
+	// Archived is whether the project is archived, i.e., read-only.
+	Archived bool `json:"archived"`
+	// Fork is whether the project is a fork of another project.
+	Fork bool `json:"fork"`
+	// Parent is the name of the project that this project is a fork of, if known.
+	Parent string `json:"parent,omitempty"`
+	// Duplicates are the names of the projects that were found to be the same
+	// project (e.g., forks or mirrors) and were collapsed onto this project.
+	Duplicates []string `json:"duplicates,omitempty"`
+	// Topics are the topics or tags of the project.
+	Topics []string `json:"topics,omitempty"`
+	// CreatedAt is when the project was created, if known.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// PushedAt is when the project was last pushed to, if known.
+	PushedAt *time.Time `json:"pushed_at,omitempty"`
+	// Size is the size of the project in bytes.
+	Size int64 `json:"size"`
+
+	// Attributes are additional, source-specific attributes of the project.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Clone returns a deep copy of m.
+func (m *Metadata) Clone() *Metadata {
+	if m == nil {
+		return nil
+	}
+
+	c := *m
+
+	if m.Stars != nil {
+		n := *m.Stars
+		c.Stars = &n
+	}
+
+	if m.Topics != nil {
+		c.Topics = append([]string(nil), m.Topics...)
+	}
+
+	if m.Duplicates != nil {
+		c.Duplicates = append([]string(nil), m.Duplicates...)
+	}
+
+	if m.CreatedAt != nil {
+		t := *m.CreatedAt
+		c.CreatedAt = &t
+	}
+
+	if m.PushedAt != nil {
+		t := *m.PushedAt
+		c.PushedAt = &t
+	}
+
+	if m.Attributes != nil {
+		c.Attributes = make(map[string]string, len(m.Attributes))
+		for k, v := range m.Attributes {
+			c.Attributes[k] = v
+		}
+	}
+
+	return &c
+}
+
+// Describer is implemented by projects that have metadata.
+type Describer interface {
+	// Metadata returns a copy of the metadata of the project.
+	Metadata() *Metadata
+}
+
+// MetadataOf returns the metadata of a project. Projects that do not have metadata
+// return empty metadata, so that the result is never nil.
+func MetadataOf(p Backend) *Metadata {
+	if d, ok := p.(Describer); ok {
+		if m := d.Metadata(); m != nil {
+			return m
+		}
+	}
+
+	return &Metadata{}
+}
@@ -0,0 +1,66 @@
+package project
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func intPtr(n int) *int { return &n }
+
+func Test_Metadata_MarshalJSON(t *testing.T) {
+	created := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var tests = map[string]struct {
+		input *Metadata
+		want  string
+	}{
+		"unknown": {
+			input: &Metadata{},
+			want:  `{"archived":false,"fork":false,"size":0}`,
+		},
+
+		"unknown_times": {
+			input: &Metadata{Stars: intPtr(1)},
+			want:  `{"stars":1,"archived":false,"fork":false,"size":0}`,
+		},
+
+		"known_times": {
+			input: &Metadata{Stars: intPtr(1), CreatedAt: &created},
+			want:  `{"stars":1,"archived":false,"fork":false,"created_at":"2019-06-01T00:00:00Z","size":0}`,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, err := json.Marshal(tt.input)
+			if err != nil {
+				t.Fatalf("json.Marshal() unexpected error: %+v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("json.Marshal() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_Metadata_Clone(t *testing.T) {
+	pushed := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	m := &Metadata{Stars: intPtr(1), PushedAt: &pushed}
+
+	c := m.Clone()
+	*c.PushedAt = c.PushedAt.Add(time.Hour)
+	*c.Stars++
+
+	if !m.PushedAt.Equal(pushed) {
+		t.Errorf("Clone() shares PushedAt: \n\tgot: '%+v'\n\twant: '%+v'", m.PushedAt, pushed)
+	}
+
+	if *m.Stars != 1 {
+		t.Errorf("Clone() shares Stars: \n\tgot: '%+v'\n\twant: '%+v'", *m.Stars, 1)
+	}
+}
@@ -1,6 +1,13 @@
 package retrieval
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/src-d/go-billy.v4"
+
+	"github.com/mccurdyc/neighbor/sdk/auth"
+)
 
 // Backend is the minimal interface that must be implemented by a retriever.
 // Retrievers are responsible for obtaining projects, the base entity on which
@@ -9,15 +16,75 @@ type Backend interface {
 	Retrieve(context.Context, string, string) error
 }
 
+// FilesystemBackend is implemented by retrievers that can retrieve a project into
+// memory rather than to a directory on disk. This is useful for lightweight,
+// read-only analyses performed in-process.
+type FilesystemBackend interface {
+	RetrieveFilesystem(context.Context, string) (billy.Filesystem, error)
+}
+
 // BackendConfig contains the configuration parameters for a retrieval backend.
 type BackendConfig struct {
 	// AuthRequired is indicates the method, if one, to be used for authentication
 	// by the retrieval backend.
 	AuthMethod string
 
+	// Credentials is the provider of credentials used when AuthMethod is
+	// "credentials".
+	Credentials auth.Provider
+
 	// Config is for optional or secondary configuration.
 	Config map[string]string
 }
 
 // Factory is a factory function for constructing retrievers backend.
 type Factory func(context.Context, *BackendConfig) (Backend, error)
+
+// TransientError is used by retrievers to indicate that a retrieval failed for
+// a reason that may not occur again (e.g., a network blip or a server error) and
+// therefore, may succeed if retried.
+type TransientError struct {
+	Err error
+}
+
+func (e *TransientError) Error() string {
+	return fmt.Sprintf("transient error: %+v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// Limits are the limits that a retriever enforces on a single project. A zero
+// value disables the respective limit.
+type Limits struct {
+	// MaxSize is the maximum size in bytes of a project as reported by the source
+	// it was found at (e.g., the repository size reported by GitHub).
+	MaxSize int64
+	// MaxFiles is the maximum number of files written when retrieving a project.
+	MaxFiles int64
+	// MaxBytes is the maximum number of bytes written when retrieving a project.
+	MaxBytes int64
+}
+
+// CheckSize returns a *LimitError if size exceeds the maximum project size.
+func (l Limits) CheckSize(size int64) error {
+	if l.MaxSize > 0 && size > l.MaxSize {
+		return &LimitError{Limit: "max_size", Max: l.MaxSize, Actual: size}
+	}
+
+	return nil
+}
+
+// LimitError is returned when retrieving a project exceeds one of the limits.
+type LimitError struct {
+	// Limit is the name of the limit that was exceeded.
+	Limit  string
+	Max    int64
+	Actual int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("exceeded %s limit (%d > %d)", e.Limit, e.Actual, e.Max)
+}
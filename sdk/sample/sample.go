@@ -0,0 +1,198 @@
+// Package sample provides deterministic random sampling of projects, so that
+// research is not biased toward the projects that a search backend ranks first.
+//
+// Samples only depend on the seed and the order of the projects that are sampled
+// from, i.e., the same seed and search results always result in the same sample.
+package sample
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+const (
+	// StrategyUniform samples projects uniformly at random.
+	StrategyUniform = "uniform"
+	// StrategyStratified samples projects uniformly at random from each stratum of a
+	// metadata field in proportion to the size of the stratum.
+	StrategyStratified = "stratified"
+)
+
+// Options configure how projects are sampled. Options are recorded alongside
+// the results of a run so that the sample can be reproduced.
+type Options struct {
+	// Strategy is the sampling strategy.
+	Strategy string `json:"strategy"`
+	// Size is the number of projects to sample.
+	Size int `json:"size"`
+	// Pool is the number of search results that projects are sampled from, if
+	// bounded.
+	Pool int `json:"pool,omitempty"`
+	// Seed is the seed of the random number generator.
+	Seed int64 `json:"seed"`
+	// Stratum is the metadata field that projects are stratified by when using the
+	// stratified strategy (see Key).
+	Stratum string `json:"stratum,omitempty"`
+}
+
+// Validate returns an error if the options are invalid.
+func (o Options) Validate() error {
+	if o.Size < 1 {
+		return fmt.Errorf("sample size must be a positive integer")
+	}
+
+	if o.Pool < 0 {
+		return fmt.Errorf("sample pool must be a positive integer")
+	}
+
+	if o.Pool != 0 && o.Size > o.Pool {
+		return fmt.Errorf("sample size (%d) must not exceed the sample pool (%d)", o.Size, o.Pool)
+	}
+
+	switch o.Strategy {
+	case StrategyUniform:
+		return nil
+	case StrategyStratified:
+		_, err := Key(o.Stratum)
+		return err
+	}
+
+	return fmt.Errorf("unsupported sampling strategy '%s'", o.Strategy)
+}
+
+// Sample returns a sample of projects according to opts. The sampled projects are
+// in the order that they appear in projects.
+func Sample(projects []project.Backend, opts Options) ([]project.Backend, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+
+	switch opts.Strategy {
+	case StrategyStratified:
+		key, _ := Key(opts.Stratum)
+		return Stratified(projects, opts.Size, r, key), nil
+	}
+
+	return Uniform(projects, opts.Size, r), nil
+}
+
+// Uniform returns n projects sampled uniformly at random without replacement.
+func Uniform(projects []project.Backend, n int, r *rand.Rand) []project.Backend {
+	if n >= len(projects) {
+		return append([]project.Backend(nil), projects...)
+	}
+
+	idx := r.Perm(len(projects))[:n]
+	sort.Ints(idx)
+
+	res := make([]project.Backend, 0, n)
+	for _, i := range idx {
+		res = append(res, projects[i])
+	}
+
+	return res
+}
+
+// KeyFunc returns the stratum of a project.
+type KeyFunc func(p project.Backend) string
+
+// Key returns the KeyFunc that stratifies projects by a metadata field. The
+// supported fields are language, license, fork, archived and stars, where stars
+// are bucketed by order of magnitude (i.e., 0, 1-9, 10-99, etc.) or are unknown.
+func Key(field string) (KeyFunc, error) {
+	switch field {
+	case "language":
+		return func(p project.Backend) string { return project.MetadataOf(p).Language }, nil
+	case "license":
+		return func(p project.Backend) string { return project.MetadataOf(p).License }, nil
+	case "fork":
+		return func(p project.Backend) string { return strconv.FormatBool(project.MetadataOf(p).Fork) }, nil
+	case "archived":
+		return func(p project.Backend) string { return strconv.FormatBool(project.MetadataOf(p).Archived) }, nil
+	case "stars":
+		return func(p project.Backend) string { return starBucket(project.MetadataOf(p).Stars) }, nil
+	}
+
+	return nil, fmt.Errorf("unsupported stratum '%s'", field)
+}
+
+// starBucket returns the order of magnitude bucket of a number of stars.
+func starBucket(n *int) string {
+	if n == nil {
+		return "unknown"
+	}
+
+	stars := *n
+	if stars < 1 {
+		return "0"
+	}
+
+	lo := 1
+	for lo*10 <= stars {
+		lo *= 10
+	}
+
+	return fmt.Sprintf("%d-%d", lo, lo*10-1)
+}
+
+// Stratified returns n projects, sampled uniformly at random from each stratum in
+// proportion to the number of projects in the stratum. Remaining samples, due to
+// rounding, are allocated to the strata with the largest remainders.
+func Stratified(projects []project.Backend, n int, r *rand.Rand, key KeyFunc) []project.Backend {
+	if n >= len(projects) {
+		return append([]project.Backend(nil), projects...)
+	}
+
+	// strata contains the indices of the projects in each stratum.
+	strata := make(map[string][]int)
+	for i, p := range projects {
+		k := key(p)
+		strata[k] = append(strata[k], i)
+	}
+
+	keys := make([]string, 0, len(strata))
+	for k := range strata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	alloc := make(map[string]int, len(keys))
+	remainders := make(map[string]int, len(keys))
+	allocated := 0
+	for _, k := range keys {
+		share := n * len(strata[k])
+		alloc[k] = share / len(projects)
+		remainders[k] = share % len(projects)
+		allocated += alloc[k]
+	}
+
+	byRemainder := append([]string(nil), keys...)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+
+	for _, k := range byRemainder[:n-allocated] {
+		alloc[k]++
+	}
+
+	var idx []int
+	for _, k := range keys {
+		for _, i := range r.Perm(len(strata[k]))[:alloc[k]] {
+			idx = append(idx, strata[k][i])
+		}
+	}
+	sort.Ints(idx)
+
+	res := make([]project.Backend, 0, n)
+	for _, i := range idx {
+		res = append(res, projects[i])
+	}
+
+	return res
+}
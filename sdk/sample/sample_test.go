@@ -0,0 +1,178 @@
+package sample
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/src-d/go-billy.v4"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+type mockProject struct {
+	name     string
+	metadata *project.Metadata
+}
+
+func (m *mockProject) Name() string                                   { return m.name }
+func (m *mockProject) Version() string                                { return "version" }
+func (m *mockProject) RetrievalFunc() retrieval.Backend               { return nil }
+func (m *mockProject) SourceLocation() string                         { return m.name }
+func (m *mockProject) LocalLocation() string                          { return "" }
+func (m *mockProject) SetLocalLocation(string) project.Backend        { return m }
+func (m *mockProject) Filesystem() billy.Filesystem                   { return nil }
+func (m *mockProject) SetFilesystem(billy.Filesystem) project.Backend { return m }
+func (m *mockProject) Metadata() *project.Metadata                    { return m.metadata.Clone() }
+
+func intPtr(n int) *int { return &n }
+
+// newProjects returns projects with the specified number of projects per language.
+func newProjects(languages map[string]int) []project.Backend {
+	var res []project.Backend
+	for _, l := range []string{"Go", "Rust", "C"} {
+		for i := 0; i < languages[l]; i++ {
+			res = append(res, &mockProject{
+				name:     fmt.Sprintf("%s/%d", l, i),
+				metadata: &project.Metadata{Language: l, Stars: intPtr(i * 10)},
+			})
+		}
+	}
+
+	return res
+}
+
+func names(projects []project.Backend) []string {
+	res := make([]string, 0, len(projects))
+	for _, p := range projects {
+		res = append(res, p.Name())
+	}
+
+	return res
+}
+
+func Test_Sample(t *testing.T) {
+	projects := newProjects(map[string]int{"Go": 6, "Rust": 3, "C": 1})
+
+	type want struct {
+		size      int
+		languages map[string]int
+		err       error
+	}
+
+	var tests = map[string]struct {
+		input Options
+		want  want
+	}{
+		"uniform": {
+			input: Options{Strategy: StrategyUniform, Size: 4, Seed: 1},
+			want:  want{size: 4},
+		},
+
+		"uniform_larger_than_population": {
+			input: Options{Strategy: StrategyUniform, Size: 20, Seed: 1},
+			want:  want{size: 10},
+		},
+
+		"stratified": {
+			input: Options{Strategy: StrategyStratified, Size: 5, Seed: 1, Stratum: "language"},
+			want:  want{size: 5, languages: map[string]int{"Go": 3, "Rust": 1, "C": 1}},
+		},
+
+		"invalid_size": {
+			input: Options{Strategy: StrategyUniform},
+			want:  want{err: fmt.Errorf("sample size must be a positive integer")},
+		},
+
+		"pool": {
+			input: Options{Strategy: StrategyUniform, Size: 4, Pool: 10, Seed: 1},
+			want:  want{size: 4},
+		},
+
+		"larger_than_pool": {
+			input: Options{Strategy: StrategyUniform, Size: 20, Pool: 10},
+			want:  want{err: fmt.Errorf("sample size (20) must not exceed the sample pool (10)")},
+		},
+
+		"invalid_strategy": {
+			input: Options{Strategy: "systematic", Size: 1},
+			want:  want{err: fmt.Errorf("unsupported sampling strategy 'systematic'")},
+		},
+
+		"invalid_stratum": {
+			input: Options{Strategy: StrategyStratified, Size: 1, Stratum: "color"},
+			want:  want{err: fmt.Errorf("unsupported stratum 'color'")},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, gotErr := Sample(projects, tt.input)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Fatalf("Sample() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			if gotErr != nil {
+				return
+			}
+
+			if len(got) != tt.want.size {
+				t.Errorf("Sample() mismatched size: \n\tgot: '%+v'\n\twant: '%+v'", len(got), tt.want.size)
+			}
+
+			again, _ := Sample(projects, tt.input)
+			if diff := cmp.Diff(names(got), names(again)); diff != "" {
+				t.Errorf("Sample() not deterministic (-first +second):\n%s", diff)
+			}
+
+			if tt.want.languages == nil {
+				return
+			}
+
+			languages := make(map[string]int)
+			for _, p := range got {
+				languages[project.MetadataOf(p).Language]++
+			}
+
+			if diff := cmp.Diff(tt.want.languages, languages); diff != "" {
+				t.Errorf("Sample() mismatched strata (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_starBucket(t *testing.T) {
+	var tests = map[string]struct {
+		input *int
+		want  string
+	}{
+		"unknown":  {input: nil, want: "unknown"},
+		"zero":     {input: intPtr(0), want: "0"},
+		"one":      {input: intPtr(1), want: "1-9"},
+		"nine":     {input: intPtr(9), want: "1-9"},
+		"ten":      {input: intPtr(10), want: "10-99"},
+		"thousand": {input: intPtr(1500), want: "1000-9999"},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := starBucket(tt.input)
+
+			if got != tt.want {
+				t.Errorf("starBucket(%+v): \n\tgot: '%+v'\n\twant: '%+v'", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package search
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
+	"github.com/mccurdyc/neighbor/sdk/auth"
 	"github.com/mccurdyc/neighbor/sdk/project"
 )
 
@@ -26,6 +28,15 @@ const (
 	Version
 )
 
+// ErrFewerResultsThanDesired is used to indicate that it was not possible to fulfill
+// the request from the user (i.e., could not find the number of results specified
+// by the user).
+//
+// This is important to specify because, for example, in research you might want
+// to guarantee that you are analyzing _exactly_ the number of projects specifed
+// or the search query may need to be tweaked.
+var ErrFewerResultsThanDesired = fmt.Errorf("contains fewer results than desired")
+
 // Backend is the minimal interface for a search backend.
 type Backend interface {
 	Search(context.Context, string, int) ([]project.Backend, error)
@@ -43,6 +54,18 @@ type BackendConfig struct {
 	// Client is the http client to be used to connect to the search service.
 	Client *http.Client
 
+	// BaseURL is the url of the API of a self-hosted search service (e.g., GitHub
+	// Enterprise Server). An empty BaseURL uses the public service.
+	BaseURL string
+
+	// UploadURL is the upload url of a self-hosted search service. It defaults
+	// to a url derived from BaseURL.
+	UploadURL string
+
+	// Credentials is the provider of credentials used when AuthMethod is
+	// "credentials".
+	Credentials auth.Provider
+
 	// Config is for optional or secondary configuration.
 	Config map[string]string
 }
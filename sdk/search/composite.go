@@ -0,0 +1,140 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+// Operation is a set operation that combines the results of a query with the
+// results of the queries that precede it.
+type Operation = string
+
+const (
+	// Union adds the results of a query.
+	Union Operation = "union"
+	// Intersection keeps only the results that are also results of a query.
+	Intersection Operation = "intersection"
+	// Difference removes the results of a query.
+	Difference Operation = "difference"
+)
+
+// Query is a single query of a composite search.
+type Query struct {
+	// Backend is the search backend that executes the query.
+	Backend Backend
+	// Query is the search query.
+	Query string
+	// Operation is how the results of the query are combined with the results of
+	// the preceding queries. An empty Operation is a Union and the Operation of the
+	// first query is ignored.
+	Operation Operation
+	// NumDesiredResults is the number of results requested from Backend. If zero,
+	// the number of results desired from the composite search is requested.
+	NumDesiredResults int
+}
+
+// Composite is a search backend that combines the results of several queries,
+// each with its own search backend, using set operations. Projects are the same
+// if they have the same project.ID, regardless of their version.
+type Composite struct {
+	queries []Query
+}
+
+// NewComposite is a constructor that returns a composite search backend. The
+// queries are combined in order, i.e., ((q1 op2 q2) op3 q3) and so on.
+func NewComposite(queries ...Query) (*Composite, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+
+	for i, q := range queries {
+		if q.Backend == nil {
+			return nil, fmt.Errorf("query %d has no search backend", i)
+		}
+
+		switch q.Operation {
+		case "", Union, Intersection, Difference:
+		default:
+			return nil, fmt.Errorf("unsupported operation '%s' for query %d", q.Operation, i)
+		}
+	}
+
+	return &Composite{
+		queries: queries,
+	}, nil
+}
+
+// Search executes each query and combines the results. The query argument is
+// ignored because every query of a composite search has its own query.
+//
+// A query that returns fewer results than desired does not fail the search, but
+// ErrFewerResultsThanDesired is returned if the combined results are fewer than
+// numDesiredResults.
+func (c *Composite) Search(ctx context.Context, _ string, numDesiredResults int) ([]project.Backend, error) {
+	var res []project.Backend
+
+	for i, q := range c.queries {
+		n := q.NumDesiredResults
+		if n == 0 {
+			n = numDesiredResults
+		}
+
+		projects, err := q.Backend.Search(ctx, q.Query, n)
+		if err != nil && !errors.Is(err, ErrFewerResultsThanDesired) {
+			return nil, fmt.Errorf("failed to search for query %d ('%s'): %w", i, q.Query, err)
+		}
+
+		op := q.Operation
+		if i == 0 {
+			op = Union
+		}
+
+		res = combine(res, projects, op)
+	}
+
+	if len(res) < numDesiredResults {
+		return res, ErrFewerResultsThanDesired
+	}
+
+	return res[:numDesiredResults], nil
+}
+
+// combine applies op to a and b, preserving the order of a followed by b.
+func combine(a []project.Backend, b []project.Backend, op Operation) []project.Backend {
+	inB := make(map[string]struct{}, len(b))
+	for _, p := range b {
+		inB[project.IDOf(p).Key()] = struct{}{}
+	}
+
+	res := make([]project.Backend, 0, len(a)+len(b))
+	seen := make(map[string]struct{}, len(a)+len(b))
+	add := func(p project.Backend) {
+		id := project.IDOf(p).Key()
+		if _, ok := seen[id]; ok {
+			return
+		}
+
+		seen[id] = struct{}{}
+		res = append(res, p)
+	}
+
+	for _, p := range a {
+		_, ok := inB[project.IDOf(p).Key()]
+		if (op == Intersection && !ok) || (op == Difference && ok) {
+			continue
+		}
+
+		add(p)
+	}
+
+	if op == "" || op == Union {
+		for _, p := range b {
+			add(p)
+		}
+	}
+
+	return res
+}
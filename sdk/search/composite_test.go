@@ -0,0 +1,205 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/src-d/go-billy.v4"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/retrieval"
+)
+
+type mockProject struct {
+	sourceLocation string
+}
+
+func (m *mockProject) Name() string                                   { return m.sourceLocation }
+func (m *mockProject) Version() string                                { return "" }
+func (m *mockProject) RetrievalFunc() retrieval.Backend               { return nil }
+func (m *mockProject) SourceLocation() string                         { return m.sourceLocation }
+func (m *mockProject) LocalLocation() string                          { return "" }
+func (m *mockProject) SetLocalLocation(string) project.Backend        { return m }
+func (m *mockProject) Filesystem() billy.Filesystem                   { return nil }
+func (m *mockProject) SetFilesystem(billy.Filesystem) project.Backend { return m }
+
+// mockBackend returns the projects of a query.
+type mockBackend struct {
+	results map[string][]string
+	err     error
+}
+
+func (m *mockBackend) Search(_ context.Context, query string, numDesiredResults int) ([]project.Backend, error) {
+	var res []project.Backend
+	for _, l := range m.results[query] {
+		if len(res) >= numDesiredResults {
+			break
+		}
+		res = append(res, &mockProject{sourceLocation: l})
+	}
+
+	if m.err != nil {
+		return res, m.err
+	}
+
+	if len(res) < numDesiredResults {
+		return res, ErrFewerResultsThanDesired
+	}
+
+	return res, nil
+}
+
+func Test_Composite_Search(t *testing.T) {
+	github := &mockBackend{results: map[string][]string{
+		"a": {"https://github.com/owner/one.git", "https://github.com/owner/two.git", "https://github.com/owner/three.git"},
+		"b": {"https://github.com/Owner/Two.git", "https://github.com/owner/four.git"},
+	}}
+
+	corpus := &mockBackend{results: map[string][]string{
+		"*": {"git@github.com:owner/three.git", "github.com/owner/five"},
+	}}
+
+	type input struct {
+		queries           []Query
+		numDesiredResults int
+	}
+
+	type want struct {
+		locations []string
+		err       error
+	}
+
+	var tests = map[string]struct {
+		input input
+		want  want
+	}{
+		"union": {
+			input: input{
+				queries: []Query{
+					{Backend: github, Query: "a"},
+					{Backend: github, Query: "b", Operation: Union},
+				},
+				numDesiredResults: 4,
+			},
+			want: want{
+				locations: []string{"https://github.com/owner/one.git", "https://github.com/owner/two.git", "https://github.com/owner/three.git", "https://github.com/owner/four.git"},
+			},
+		},
+
+		"difference_across_backends": {
+			input: input{
+				queries: []Query{
+					{Backend: github, Query: "a"},
+					{Backend: github, Query: "b", Operation: Difference},
+					{Backend: corpus, Query: "*", Operation: Difference},
+				},
+				numDesiredResults: 1,
+			},
+			want: want{
+				locations: []string{"https://github.com/owner/one.git"},
+			},
+		},
+
+		"intersection_fewer_than_desired": {
+			input: input{
+				queries: []Query{
+					{Backend: github, Query: "a"},
+					{Backend: github, Query: "b", Operation: Intersection},
+				},
+				numDesiredResults: 3,
+			},
+			want: want{
+				locations: []string{"https://github.com/owner/two.git"},
+				err:       ErrFewerResultsThanDesired,
+			},
+		},
+
+		"query_error": {
+			input: input{
+				queries: []Query{
+					{Backend: &mockBackend{err: fmt.Errorf("rate limited")}, Query: "a"},
+				},
+				numDesiredResults: 1,
+			},
+			want: want{
+				err: fmt.Errorf("failed to search for query 0 ('a'): rate limited"),
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			c, err := NewComposite(tt.input.queries...)
+			if err != nil {
+				t.Fatalf("failed to create composite search: %+v", err)
+			}
+
+			got, gotErr := c.Search(context.TODO(), "", tt.input.numDesiredResults)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want.err); !ok {
+				t.Errorf("Search() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want.err)
+			}
+
+			var locations []string
+			for _, p := range got {
+				locations = append(locations, p.SourceLocation())
+			}
+
+			if diff := cmp.Diff(tt.want.locations, locations); diff != "" {
+				t.Errorf("Search() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_NewComposite(t *testing.T) {
+	var tests = map[string]struct {
+		input []Query
+		want  error
+	}{
+		"no_queries": {
+			input: nil,
+			want:  fmt.Errorf("at least one query is required"),
+		},
+
+		"no_backend": {
+			input: []Query{{Query: "a"}},
+			want:  fmt.Errorf("query 0 has no search backend"),
+		},
+
+		"unsupported_operation": {
+			input: []Query{{Backend: &mockBackend{}}, {Backend: &mockBackend{}, Operation: "xor"}},
+			want:  fmt.Errorf("unsupported operation 'xor' for query 1"),
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			_, gotErr := NewComposite(tt.input...)
+
+			// https://github.com/google/go-cmp/issues/24
+			errorCmp := func(x, y error) bool {
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			}
+
+			if ok := errorCmp(gotErr, tt.want); !ok {
+				t.Errorf("NewComposite() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mccurdyc/neighbor/sdk/project"
+	"github.com/mccurdyc/neighbor/sdk/sample"
+)
+
+// journalFile is the name of the file in the projects directory that journals the
+// projects that were found by a search, retrieved into it and run.
+const journalFile = "neighbor.jsonl"
+
+// The phases of a project that are journaled.
+const (
+	phaseSearch    = "search"
+	phaseRetrieval = "retrieval"
+	phaseRun       = "run"
+)
+
+// Manifest lists the projects found by a search. The search command writes a
+// manifest, which the retrieve command journals in the projects directory along
+// with the status of retrieving and running each project.
+type Manifest struct {
+	NeighborVersion string          `json:"neighbor_version"`
+	Query           string          `json:"query,omitempty"`
+	Sample          *sample.Options `json:"sample,omitempty"`
+	Projects        []Project       `json:"projects"`
+}
+
+// Project is a project of a manifest.
+type Project struct {
+	ID       project.ID        `json:"id"`
+	Name     string            `json:"name"`
+	Source   string            `json:"source"`
+	Metadata *project.Metadata `json:"metadata,omitempty"`
+	// Origin is the search backend that found the project, which determines how it
+	// is retrieved.
+	Origin *Origin `json:"origin,omitempty"`
+
+	// Version is the version that was retrieved, which is the version found by
+	// the search, unless the search did not pin one.
+	Version string `json:"version,omitempty"`
+	// Dir is the directory, relative to the projects directory, that the project
+	// was retrieved into.
+	Dir string `json:"dir,omitempty"`
+
+	Retrieval *Status `json:"retrieval,omitempty"`
+	Run       *Status `json:"run,omitempty"`
+}
+
+// Origin is a search backend with the config values that the retrieval backend of
+// the projects that it finds depends on (e.g., the archive of a corpus).
+type Origin struct {
+	Backend string            `json:"backend"`
+	Config  map[string]string `json:"config,omitempty"`
+}
+
+// Status is the result of retrieving a project or running a command on it.
+type Status struct {
+	OK bool `json:"ok"`
+	// Skipped is whether the project exceeded one of the retrieval limits.
+	Skipped   bool          `json:"skipped,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	Attempts  int           `json:"attempts,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// newProject returns the manifest project of a search result.
+func newProject(p project.Backend) Project {
+	return Project{
+		ID:       project.IDOf(p),
+		Name:     p.Name(),
+		Source:   p.SourceLocation(),
+		Metadata: project.MetadataOf(p),
+		Origin:   originOf(p),
+		Version:  p.Version(),
+	}
+}
+
+// newStatus returns the status of an operation that started at start and failed
+// with err, if err is not nil.
+func newStatus(start time.Time, err error) *Status {
+	s := &Status{
+		OK:        err == nil,
+		StartedAt: start.UTC(),
+		Duration:  time.Since(start),
+	}
+
+	if err != nil {
+		s.Error = err.Error()
+	}
+
+	return s
+}
+
+// readManifest reads a manifest from the file at p or stdin, if p is "-".
+func readManifest(p string) (*Manifest, error) {
+	var r io.Reader = os.Stdin
+	if p != "-" {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r = f
+	}
+
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %+v", err)
+	}
+
+	return &m, nil
+}
+
+// writeManifest writes m to the file at p or stdout, if p is "-". The file is
+// replaced atomically, so that it is never left partially written.
+func writeManifest(p string, m *Manifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if p == "-" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(p), "."+filepath.Base(p))
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return os.Rename(f.Name(), p)
+}
+
+// stateKey identifies a version of a project in the journal, regardless of the
+// search backend that found it.
+func stateKey(id project.ID) string {
+	return fmt.Sprintf("%s@%s", id.Key(), id.Version)
+}
+
+// journalEntry is a line of the journal. A search entry lists the projects, with
+// their status so far, that later entries update, until the next search entry.
+type journalEntry struct {
+	Phase    string    `json:"phase"`
+	Manifest *Manifest `json:"manifest,omitempty"`
+
+	// Key is the stateKey of the project of a retrieval or run entry.
+	Key     string  `json:"key,omitempty"`
+	Version string  `json:"version,omitempty"`
+	Dir     string  `json:"dir,omitempty"`
+	Status  *Status `json:"status,omitempty"`
+}
+
+// journal appends entries to the journal of a projects directory. Each entry is
+// synced before the next project is processed, so that an interrupted run can be
+// resumed from the last project that it processed.
+type journal struct {
+	f *os.File
+}
+
+// openJournal opens the journal of the projects directory root for appending.
+func openJournal(root string) (*journal, error) {
+	p := filepath.Join(root, journalFile)
+
+	// a partial entry of an interrupted run would corrupt the entry appended to it.
+	b, err := ioutil.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read journal: %+v", err)
+	}
+
+	if len(b) != 0 && b[len(b)-1] != '\n' {
+		if err := os.Truncate(p, int64(bytes.LastIndexByte(b, '\n')+1)); err != nil {
+			return nil, fmt.Errorf("failed to truncate partial journal entry: %+v", err)
+		}
+	}
+
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %+v", err)
+	}
+
+	return &journal{f: f}, nil
+}
+
+func (j *journal) append(e journalEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal: %+v", err)
+	}
+
+	return j.f.Sync()
+}
+
+// search journals the projects of m, along with their status, which later entries
+// update.
+func (j *journal) search(m *Manifest) error {
+	return j.append(journalEntry{Phase: phaseSearch, Manifest: m})
+}
+
+// retrieval journals the status of retrieving p.
+func (j *journal) retrieval(p *Project) error {
+	return j.append(journalEntry{Phase: phaseRetrieval, Key: stateKey(p.ID), Version: p.Version, Dir: p.Dir, Status: p.Retrieval})
+}
+
+// run journals the status of running the command on p.
+func (j *journal) run(p *Project) error {
+	return j.append(journalEntry{Phase: phaseRun, Key: stateKey(p.ID), Status: p.Run})
+}
+
+func (j *journal) Close() error {
+	return j.f.Close()
+}
+
+// readState replays the journal of the projects directory root and returns the
+// projects of the latest search entry with their latest status, or nil if nothing was
+// searched. A truncated last entry, e.g., of an interrupted run, is ignored.
+func readState(root string) (*Manifest, error) {
+	f, err := os.Open(filepath.Join(root, journalFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %+v", err)
+	}
+	defer f.Close()
+
+	var m *Manifest
+	var entries []journalEntry
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// the last entry was not completely written.
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read journal: %+v", err)
+		}
+
+		var e journalEntry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return nil, fmt.Errorf("invalid journal entry on line %d: %+v", line, err)
+		}
+
+		if e.Phase == phaseSearch {
+			m, entries = e.Manifest, nil
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	if m == nil {
+		return nil, nil
+	}
+
+	projects := make(map[string]*Project, len(m.Projects))
+	for i := range m.Projects {
+		projects[stateKey(m.Projects[i].ID)] = &m.Projects[i]
+	}
+
+	for _, e := range entries {
+		p, ok := projects[e.Key]
+		if !ok {
+			continue
+		}
+
+		switch e.Phase {
+		case phaseRetrieval:
+			p.Retrieval, p.Version, p.Dir = e.Status, e.Version, e.Dir
+			// a project that is retrieved again has not been run yet.
+			p.Run = nil
+		case phaseRun:
+			p.Run = e.Status
+		}
+	}
+
+	return m, nil
+}
+
+// mergeState copies the status of the projects of prev to the same projects of m.
+func mergeState(m *Manifest, prev *Manifest) {
+	if prev == nil {
+		return
+	}
+
+	projects := make(map[string]Project, len(prev.Projects))
+	for _, p := range prev.Projects {
+		projects[stateKey(p.ID)] = p
+	}
+
+	for i := range m.Projects {
+		p := &m.Projects[i]
+
+		if prevP, ok := projects[stateKey(p.ID)]; ok {
+			p.Retrieval, p.Run, p.Version, p.Dir = prevP.Retrieval, prevP.Run, prevP.Version, prevP.Dir
+		}
+	}
+}
+
+// Summary summarizes the status of the projects of a manifest.
+type Summary struct {
+	Projects  int `json:"projects"`
+	Retrieved int `json:"retrieved"`
+	Skipped   int `json:"skipped"`
+	// RetrievalFailed does not include the skipped projects.
+	RetrievalFailed int `json:"retrieval_failed"`
+	RunSucceeded    int `json:"run_succeeded"`
+	RunFailed       int `json:"run_failed"`
+	// Failures maps the name of each project that failed to the error.
+	Failures map[string]string `json:"failures,omitempty"`
+}
+
+// summarize returns the summary of m.
+func summarize(m *Manifest) Summary {
+	s := Summary{Projects: len(m.Projects)}
+
+	fail := func(name string, err string) {
+		if s.Failures == nil {
+			s.Failures = make(map[string]string)
+		}
+		s.Failures[name] = err
+	}
+
+	for _, p := range m.Projects {
+		switch {
+		case p.Retrieval == nil:
+		case p.Retrieval.OK:
+			s.Retrieved++
+		case p.Retrieval.Skipped:
+			s.Skipped++
+			fail(p.Name, p.Retrieval.Error)
+		default:
+			s.RetrievalFailed++
+			fail(p.Name, p.Retrieval.Error)
+		}
+
+		switch {
+		case p.Run == nil:
+		case p.Run.OK:
+			s.RunSucceeded++
+		default:
+			s.RunFailed++
+			fail(p.Name, p.Run.Error)
+		}
+	}
+
+	return s
+}
+
+// writeSummary writes s to w as text.
+func writeSummary(w io.Writer, s Summary) {
+	fmt.Fprintf(w, "projects: %d\n", s.Projects)
+	fmt.Fprintf(w, "retrieved: %d\n", s.Retrieved)
+	fmt.Fprintf(w, "skipped: %d\n", s.Skipped)
+	fmt.Fprintf(w, "retrieval failed: %d\n", s.RetrievalFailed)
+	fmt.Fprintf(w, "run succeeded: %d\n", s.RunSucceeded)
+	fmt.Fprintf(w, "run failed: %d\n", s.RunFailed)
+
+	if len(s.Failures) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(s.Failures))
+	for name := range s.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "failures:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %s\n", name, s.Failures[name])
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mccurdyc/neighbor/sdk/project"
+)
+
+func Test_summarize(t *testing.T) {
+	var tests = map[string]struct {
+		input *Manifest
+		want  Summary
+	}{
+		"empty": {
+			input: &Manifest{},
+			want:  Summary{},
+		},
+
+		"searched_only": {
+			input: &Manifest{Projects: []Project{{Name: "a/b"}, {Name: "c/d"}}},
+			want:  Summary{Projects: 2},
+		},
+
+		"retrieved_and_run": {
+			input: &Manifest{
+				Projects: []Project{
+					{Name: "a/ok", Retrieval: &Status{OK: true}, Run: &Status{OK: true}},
+					{Name: "a/run_failed", Retrieval: &Status{OK: true}, Run: &Status{Error: "exit status 1"}},
+					{Name: "a/not_run", Retrieval: &Status{OK: true}},
+					{Name: "a/skipped", Retrieval: &Status{Skipped: true, Error: "exceeded max_size"}},
+					{Name: "a/failed", Retrieval: &Status{Error: "not found"}},
+				},
+			},
+			want: Summary{
+				Projects:        5,
+				Retrieved:       3,
+				Skipped:         1,
+				RetrievalFailed: 1,
+				RunSucceeded:    1,
+				RunFailed:       1,
+				Failures: map[string]string{
+					"a/run_failed": "exit status 1",
+					"a/skipped":    "exceeded max_size",
+					"a/failed":     "not found",
+				},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got := summarize(tt.input)
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("summarize() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_readState(t *testing.T) {
+	a := Project{ID: project.ID{Host: "github.com", Namespace: "a", Name: "b", Version: "v1"}, Name: "a/b", Version: "v1"}
+	c := Project{ID: project.ID{Host: "github.com", Namespace: "c", Name: "d"}, Name: "c/d"}
+
+	ok := &Status{OK: true}
+	failed := &Status{Error: "exit status 1"}
+
+	type input struct {
+		entries []journalEntry
+		// partial is appended to the journal without a trailing newline.
+		partial string
+	}
+
+	var tests = map[string]struct {
+		input   input
+		want    *Manifest
+		wantErr error
+	}{
+		"no_journal": {
+			input: input{},
+			want:  nil,
+		},
+
+		"searched_only": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a, c}}},
+				},
+			},
+			want: &Manifest{Projects: []Project{a, c}},
+		},
+
+		"retrieved_and_run": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a, c}}},
+					{Phase: phaseRetrieval, Key: "github.com/a/b@v1", Version: "v1", Dir: "github.com/a/b@v1", Status: ok},
+					{Phase: phaseRun, Key: "github.com/a/b@v1", Status: failed},
+					{Phase: phaseRetrieval, Key: "github.com/c/d@", Version: "abc", Dir: "github.com/c/d", Status: ok},
+				},
+			},
+			want: &Manifest{
+				Projects: []Project{
+					{ID: a.ID, Name: "a/b", Version: "v1", Dir: "github.com/a/b@v1", Retrieval: ok, Run: failed},
+					{ID: c.ID, Name: "c/d", Version: "abc", Dir: "github.com/c/d", Retrieval: ok},
+				},
+			},
+		},
+
+		"retrieved_again": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a}}},
+					{Phase: phaseRetrieval, Key: "github.com/a/b@v1", Version: "v1", Dir: "a", Status: ok},
+					{Phase: phaseRun, Key: "github.com/a/b@v1", Status: failed},
+					{Phase: phaseRetrieval, Key: "github.com/a/b@v1", Version: "v1", Dir: "a", Status: ok},
+				},
+			},
+			want: &Manifest{Projects: []Project{{ID: a.ID, Name: "a/b", Version: "v1", Dir: "a", Retrieval: ok}}},
+		},
+
+		"new_search": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a}}},
+					{Phase: phaseRetrieval, Key: "github.com/a/b@v1", Version: "v1", Dir: "a", Status: ok},
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a, c}}},
+				},
+			},
+			want: &Manifest{Projects: []Project{a, c}},
+		},
+
+		"interrupted_resume": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a, c}}},
+					{Phase: phaseRetrieval, Key: "github.com/a/b@v1", Version: "v1", Dir: "a", Status: ok},
+					{Phase: phaseRun, Key: "github.com/a/b@v1", Status: ok},
+					// a resumed run journals the statuses that it resumes and is
+					// interrupted before it gets to a again.
+					{Phase: phaseSearch, Manifest: &Manifest{
+						Projects: []Project{
+							{ID: a.ID, Name: "a/b", Version: "v1", Dir: "a", Retrieval: ok, Run: ok},
+							c,
+						},
+					}},
+				},
+			},
+			want: &Manifest{
+				Projects: []Project{
+					{ID: a.ID, Name: "a/b", Version: "v1", Dir: "a", Retrieval: ok, Run: ok},
+					c,
+				},
+			},
+		},
+
+		"truncated_entry": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a}}},
+				},
+				partial: `{"phase":"retrieval","key":"github.com/a/b@v1","sta`,
+			},
+			want: &Manifest{Projects: []Project{a}},
+		},
+
+		"unknown_project": {
+			input: input{
+				entries: []journalEntry{
+					{Phase: phaseSearch, Manifest: &Manifest{Projects: []Project{a}}},
+					{Phase: phaseRun, Key: "github.com/e/f@", Status: ok},
+				},
+			},
+			want: &Manifest{Projects: []Project{a}},
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "neighbor-state")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			if len(tt.input.entries) != 0 {
+				j, err := openJournal(dir)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, e := range tt.input.entries {
+					if err := j.append(e); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				if _, err := j.f.WriteString(tt.input.partial); err != nil {
+					t.Fatal(err)
+				}
+				j.Close()
+			}
+
+			got, gotErr := readState(dir)
+
+			errorCmp := cmp.Comparer(func(x, y error) bool {
+				// https://github.com/google/go-cmp/issues/24
+				if x == nil || y == nil {
+					return x == nil && y == nil
+				}
+				return x.Error() == y.Error()
+			})
+
+			if diff := cmp.Diff(tt.wantErr, gotErr, errorCmp); diff != "" {
+				t.Errorf("readState() \n\tgotErr: '%+v'\n\twantErr: '%+v'", gotErr, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("readState() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_openJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "neighbor-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	p := filepath.Join(dir, journalFile)
+	if err := ioutil.WriteFile(p, []byte("{\"phase\":\"search\",\"manifest\":{\"projects\":[]}}\n{\"pha"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := openJournal(dir)
+	if err != nil {
+		t.Fatalf("openJournal() failed: %+v", err)
+	}
+
+	if err := j.search(&Manifest{Projects: []Project{{Name: "a/b"}}}); err != nil {
+		t.Fatal(err)
+	}
+	j.Close()
+
+	got, err := readState(dir)
+	if err != nil {
+		t.Fatalf("readState() failed: %+v", err)
+	}
+
+	want := &Manifest{Projects: []Project{{Name: "a/b"}}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("readState() mismatch (-want +got):\n%s", diff)
+	}
+}
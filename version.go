@@ -0,0 +1,5 @@
+package main
+
+// version is the version of neighbor. It is set at build time, e.g.,
+// `go build -ldflags "-X main.version=v0.1.0"`.
+var version = "dev"